@@ -0,0 +1,245 @@
+package netpbm
+
+import (
+	"fmt"
+	"math"
+)
+
+// Overflow selects what happens to results outside 0..Max.
+type Overflow int
+
+const (
+	// Saturate clamps results to 0..Max.
+	Saturate Overflow = iota
+	// Wrap takes results modulo Max+1.
+	Wrap
+)
+
+// arithmetic returns f(a, b) brought back to 0..max according to overflow.
+func arithmetic(a, b uint8, max int, overflow Overflow, f func(a, b float64) float64) uint8{
+	v := math.Round(f(float64(a), float64(b)))
+	if overflow == Wrap {
+		m := float64(min(max, 255) + 1)
+		v = math.Mod(v, m)
+		if v < 0 {
+			v += m
+		}
+		return uint8(v)
+	}
+	return clampToMax(v, max)
+}
+
+// combinePGM replaces each pixel of a with f of the pixels of a and b.
+func combinePGM(a, b *PGM, overflow Overflow, f func(a, b float64) float64) error{
+	if a.Width != b.Width || a.Height != b.Height {
+		return fmt.Errorf("image sizes differ: %dx%d and %dx%d", a.Width, a.Height, b.Width, b.Height)
+	}
+	for i := 0; i < a.Height; i++ {
+		for j := 0; j < a.Width; j++ {
+			a.Data[i][j] = arithmetic(a.Data[i][j], b.Data[i][j], a.Max, overflow, f)
+		}
+	}
+	return nil
+}
+
+// combinePPM replaces each channel of each pixel of a with f of the channels of a and b.
+func combinePPM(a, b *PPM, overflow Overflow, f func(a, b float64) float64) error{
+	if a.Width != b.Width || a.Height != b.Height {
+		return fmt.Errorf("image sizes differ: %dx%d and %dx%d", a.Width, a.Height, b.Width, b.Height)
+	}
+	for i := 0; i < a.Height; i++ {
+		for j := 0; j < a.Width; j++ {
+			p, q := a.Data[i][j], b.Data[i][j]
+			a.Data[i][j] = Pixel{
+				arithmetic(p.R, q.R, a.Max, overflow, f),
+				arithmetic(p.G, q.G, a.Max, overflow, f),
+				arithmetic(p.B, q.B, a.Max, overflow, f),
+			}
+		}
+	}
+	return nil
+}
+
+// add returns a + b.
+func add(a, b float64) float64{
+	return a + b
+}
+
+// subtract returns a - b.
+func subtract(a, b float64) float64{
+	return a - b
+}
+
+// absDiff returns |a - b|.
+func absDiff(a, b float64) float64{
+	return math.Abs(a - b)
+}
+
+// multiply returns the normalized product a*b/max, so that multiplying by Max is the identity.
+func multiply(max int) func(a, b float64) float64{
+	return func(a, b float64) float64{
+		return a * b / float64(max)
+	}
+}
+
+// Add adds other to the PGM image pixel by pixel.
+func (pgm *PGM) Add(other *PGM, overflow Overflow) error{
+	return combinePGM(pgm, other, overflow, add)
+}
+
+// Subtract subtracts other from the PGM image pixel by pixel.
+func (pgm *PGM) Subtract(other *PGM, overflow Overflow) error{
+	return combinePGM(pgm, other, overflow, subtract)
+}
+
+// Multiply multiplies the PGM image by other pixel by pixel, normalized so that Max is 1.
+func (pgm *PGM) Multiply(other *PGM, overflow Overflow) error{
+	return combinePGM(pgm, other, overflow, multiply(pgm.Max))
+}
+
+// AbsDiff replaces each pixel of the PGM image with its absolute difference with other.
+func (pgm *PGM) AbsDiff(other *PGM) error{
+	return combinePGM(pgm, other, Saturate, absDiff)
+}
+
+// Minimum replaces each pixel of the PGM image with the smaller of itself and other.
+func (pgm *PGM) Minimum(other *PGM) error{
+	return combinePGM(pgm, other, Saturate, math.Min)
+}
+
+// Maximum replaces each pixel of the PGM image with the larger of itself and other.
+func (pgm *PGM) Maximum(other *PGM) error{
+	return combinePGM(pgm, other, Saturate, math.Max)
+}
+
+// AddScalar adds value to each pixel of the PGM image.
+func (pgm *PGM) AddScalar(value float64, overflow Overflow){
+	pgm.applyScalar(overflow, func(v float64) float64{
+		return v + value
+	})
+}
+
+// MultiplyScalar multiplies each pixel of the PGM image by factor.
+func (pgm *PGM) MultiplyScalar(factor float64, overflow Overflow){
+	pgm.applyScalar(overflow, func(v float64) float64{
+		return v * factor
+	})
+}
+
+// applyScalar replaces each pixel v of the PGM image with f(v).
+func (pgm *PGM) applyScalar(overflow Overflow, f func(v float64) float64){
+	for i := 0; i < pgm.Height; i++ {
+		for j := 0; j < pgm.Width; j++ {
+			pgm.Data[i][j] = arithmetic(pgm.Data[i][j], 0, pgm.Max, overflow, func(a, _ float64) float64{
+				return f(a)
+			})
+		}
+	}
+}
+
+// Add adds other to the PPM image channel by channel.
+func (ppm *PPM) Add(other *PPM, overflow Overflow) error{
+	return combinePPM(ppm, other, overflow, add)
+}
+
+// Subtract subtracts other from the PPM image channel by channel.
+func (ppm *PPM) Subtract(other *PPM, overflow Overflow) error{
+	return combinePPM(ppm, other, overflow, subtract)
+}
+
+// Multiply multiplies the PPM image by other channel by channel, normalized so that Max is 1.
+func (ppm *PPM) Multiply(other *PPM, overflow Overflow) error{
+	return combinePPM(ppm, other, overflow, multiply(ppm.Max))
+}
+
+// AbsDiff replaces each channel of the PPM image with its absolute difference with other.
+func (ppm *PPM) AbsDiff(other *PPM) error{
+	return combinePPM(ppm, other, Saturate, absDiff)
+}
+
+// Minimum replaces each channel of the PPM image with the smaller of itself and other.
+func (ppm *PPM) Minimum(other *PPM) error{
+	return combinePPM(ppm, other, Saturate, math.Min)
+}
+
+// Maximum replaces each channel of the PPM image with the larger of itself and other.
+func (ppm *PPM) Maximum(other *PPM) error{
+	return combinePPM(ppm, other, Saturate, math.Max)
+}
+
+// AddScalar adds value to each channel of the PPM image.
+func (ppm *PPM) AddScalar(value float64, overflow Overflow){
+	ppm.applyScalar(overflow, func(v float64) float64{
+		return v + value
+	})
+}
+
+// MultiplyScalar multiplies each channel of the PPM image by factor.
+func (ppm *PPM) MultiplyScalar(factor float64, overflow Overflow){
+	ppm.applyScalar(overflow, func(v float64) float64{
+		return v * factor
+	})
+}
+
+// applyScalar replaces each channel v of the PPM image with f(v).
+func (ppm *PPM) applyScalar(overflow Overflow, f func(v float64) float64){
+	g := func(a, _ float64) float64{
+		return f(a)
+	}
+	for i := 0; i < ppm.Height; i++ {
+		for j := 0; j < ppm.Width; j++ {
+			p := ppm.Data[i][j]
+			ppm.Data[i][j] = Pixel{
+				arithmetic(p.R, 0, ppm.Max, overflow, g),
+				arithmetic(p.G, 0, ppm.Max, overflow, g),
+				arithmetic(p.B, 0, ppm.Max, overflow, g),
+			}
+		}
+	}
+}
+
+// combinePBM replaces each pixel of a with f of the pixels of a and b.
+func combinePBM(a, b *PBM, f func(a, b bool) bool) error{
+	if a.Width != b.Width || a.Height != b.Height {
+		return fmt.Errorf("image sizes differ: %dx%d and %dx%d", a.Width, a.Height, b.Width, b.Height)
+	}
+	for i := 0; i < a.Height; i++ {
+		for j := 0; j < a.Width; j++ {
+			a.Data[i][j] = f(a.Data[i][j], b.Data[i][j])
+		}
+	}
+	return nil
+}
+
+// And keeps the pixels set in both the PBM image and other.
+func (pbm *PBM) And(other *PBM) error{
+	return combinePBM(pbm, other, func(a, b bool) bool{
+		return a && b
+	})
+}
+
+// Or sets the pixels set in either the PBM image or other.
+func (pbm *PBM) Or(other *PBM) error{
+	return combinePBM(pbm, other, func(a, b bool) bool{
+		return a || b
+	})
+}
+
+// Xor sets the pixels set in exactly one of the PBM image and other.
+func (pbm *PBM) Xor(other *PBM) error{
+	return combinePBM(pbm, other, func(a, b bool) bool{
+		return a != b
+	})
+}
+
+// AndNot unsets the pixels of the PBM image that are set in other.
+func (pbm *PBM) AndNot(other *PBM) error{
+	return combinePBM(pbm, other, func(a, b bool) bool{
+		return a && !b
+	})
+}
+
+// Not inverts every pixel of the PBM image. It is the same as Invert.
+func (pbm *PBM) Not(){
+	pbm.Invert()
+}
\ No newline at end of file
@@ -0,0 +1,86 @@
+package netpbm
+
+import "fmt"
+
+// Channel identifies a channel of a PPM image.
+type Channel int
+
+const (
+	ChannelRed Channel = iota
+	ChannelGreen
+	ChannelBlue
+)
+
+// channelToPGM wraps a channel of the PPM image in a PGM image with the same Max.
+func (ppm *PPM) channelToPGM(data [][]uint8) *PGM{
+	return &PGM{Data: data, Width: ppm.Width, Height: ppm.Height, MagicNumber: "P2", Max: ppm.Max}
+}
+
+// Split returns the R, G and B channels of the PPM image as three PGM images.
+func (ppm *PPM) Split() (r, g, b *PGM){
+	rc, gc, bc := ppm.channels()
+	return ppm.channelToPGM(rc), ppm.channelToPGM(gc), ppm.channelToPGM(bc)
+}
+
+// Merge builds a PPM image from three PGM images of the same size used as R, G and B.
+// The Max of the result is the largest Max of the three.
+func Merge(r, g, b *PGM) (*PPM, error){
+	if r.Width != g.Width || r.Width != b.Width || r.Height != g.Height || r.Height != b.Height {
+		return nil, fmt.Errorf("cannot merge channels of different sizes: %dx%d, %dx%d, %dx%d", r.Width, r.Height, g.Width, g.Height, b.Width, b.Height)
+	}
+	ppm := newPPM(r.Width, r.Height, max(r.Max, g.Max, b.Max))
+	ppm.setChannels(r.Data, g.Data, b.Data)
+	return ppm, nil
+}
+
+// Swizzle reorders the channels of the PPM image. order lists, for the new R, G and B
+// channels, the letter of the old channel to use: "BGR" swaps red and blue, "RRR"
+// copies red to every channel.
+func (ppm *PPM) Swizzle(order string) error{
+	if len(order) != 3 {
+		return fmt.Errorf("invalid channel order '%s': expected 3 channels", order)
+	}
+	var source [3]int
+	for i, c := range order {
+		switch c {
+		case 'R', 'r':
+			source[i] = 0
+		case 'G', 'g':
+			source[i] = 1
+		case 'B', 'b':
+			source[i] = 2
+		default:
+			return fmt.Errorf("invalid channel order '%s': unknown channel '%c'", order, c)
+		}
+	}
+	for i := 0; i < ppm.Height; i++ {
+		for j := 0; j < ppm.Width; j++ {
+			p := ppm.Data[i][j]
+			ppm.Data[i][j] = Pixel{channelValue(p, source[0]), channelValue(p, source[1]), channelValue(p, source[2])}
+		}
+	}
+	return nil
+}
+
+// ApplyToChannel runs f on one channel of the PPM image, given as a PGM image, and
+// writes the result back. f may modify the PGM in place, but must keep its size.
+func (ppm *PPM) ApplyToChannel(channel Channel, f func(pgm *PGM)) error{
+	r, g, b := ppm.Split()
+	var pgm *PGM
+	switch channel {
+	case ChannelRed:
+		pgm = r
+	case ChannelGreen:
+		pgm = g
+	case ChannelBlue:
+		pgm = b
+	default:
+		return fmt.Errorf("invalid channel %d", channel)
+	}
+	f(pgm)
+	if pgm.Width != ppm.Width || pgm.Height != ppm.Height {
+		return fmt.Errorf("channel size changed from %dx%d to %dx%d", ppm.Width, ppm.Height, pgm.Width, pgm.Height)
+	}
+	ppm.setChannels(r.Data, g.Data, b.Data)
+	return nil
+}
\ No newline at end of file
@@ -0,0 +1,139 @@
+package netpbm
+
+import (
+	"math"
+	"sort"
+)
+
+// ColorStop is a color of a Colormap at a position between 0 and 1.
+type ColorStop struct {
+	Position float64
+	Color    Pixel
+}
+
+// Colormap is a gradient made of color stops sorted by position.
+// Colors are given for a max value of 255.
+type Colormap []ColorStop
+
+// NewGradient returns a colormap with the colors evenly spaced from 0 to 1.
+func NewGradient(colors ...Pixel) Colormap{
+	cm := make(Colormap, len(colors))
+	for i, c := range colors {
+		position := 0.0
+		if len(colors) > 1 {
+			position = float64(i) / float64(len(colors)-1)
+		}
+		cm[i] = ColorStop{position, c}
+	}
+	return cm
+}
+
+// hexGradient returns an evenly spaced colormap from 0xRRGGBB colors.
+func hexGradient(colors ...uint32) Colormap{
+	pixels := make([]Pixel, len(colors))
+	for i, c := range colors {
+		pixels[i] = Pixel{uint8(c >> 16), uint8(c >> 8), uint8(c)}
+	}
+	return NewGradient(pixels...)
+}
+
+// Built-in colormaps.
+var (
+	Viridis   = hexGradient(0x440154, 0x482878, 0x3e4a89, 0x31688e, 0x26828e, 0x1f9e89, 0x35b779, 0x6dcd59, 0xb4de2c, 0xfde725)
+	Magma     = hexGradient(0x000004, 0x180f3e, 0x451077, 0x721f81, 0x9f2f7f, 0xcd4071, 0xf1605d, 0xfd9567, 0xfec98d, 0xfcfdbf)
+	Inferno   = hexGradient(0x000004, 0x1b0c42, 0x4b0c6b, 0x781c6d, 0xa52c60, 0xcf4446, 0xed6925, 0xfb9a06, 0xf7d03c, 0xfcffa4)
+	Grayscale = hexGradient(0x000000, 0xffffff)
+	Jet       = Colormap{
+		{0, Pixel{0, 0, 128}},
+		{0.125, Pixel{0, 0, 255}},
+		{0.375, Pixel{0, 255, 255}},
+		{0.625, Pixel{255, 255, 0}},
+		{0.875, Pixel{255, 0, 0}},
+		{1, Pixel{128, 0, 0}},
+	}
+)
+
+// At returns the color of the colormap at t, clamped to [0, 1], interpolating
+// linearly between the surrounding stops.
+func (cm Colormap) At(t float64) Pixel{
+	if len(cm) == 0 {
+		return Pixel{}
+	}
+	t = math.Max(0, math.Min(1, t))
+	i := sort.Search(len(cm), func(i int) bool{
+		return cm[i].Position >= t
+	})
+	if i == 0 {
+		return cm[0].Color
+	}
+	if i == len(cm) {
+		return cm[len(cm)-1].Color
+	}
+	a, b := cm[i-1], cm[i]
+	if b.Position == a.Position {
+		return b.Color
+	}
+	f := (t - a.Position) / (b.Position - a.Position)
+	lerp := func(x, y uint8) uint8{
+		return clampToMax(float64(x)+f*(float64(y)-float64(x)), 255)
+	}
+	return Pixel{lerp(a.Color.R, b.Color.R), lerp(a.Color.G, b.Color.G), lerp(a.Color.B, b.Color.B)}
+}
+
+// ApplyColormap converts the PGM image to a PPM image with a max value of 255,
+// mapping 0 to the start of the colormap and Max to its end.
+func (pgm *PGM) ApplyColormap(cm Colormap) *PPM{
+	ppm := newPPM(pgm.Width, pgm.Height, 255)
+	lut := make([]Pixel, 256)
+	for v := range lut {
+		lut[v] = cm.At(float64(v) / float64(max(pgm.Max, 1)))
+	}
+	for i := 0; i < pgm.Height; i++ {
+		for j := 0; j < pgm.Width; j++ {
+			ppm.Data[i][j] = lut[pgm.Data[i][j]]
+		}
+	}
+	return ppm
+}
+
+// ToPPM converts the PGM image to a gray PPM image with the same Max.
+func (pgm *PGM) ToPPM() *PPM{
+	ppm := newPPM(pgm.Width, pgm.Height, pgm.Max)
+	for i := 0; i < pgm.Height; i++ {
+		for j := 0; j < pgm.Width; j++ {
+			v := pgm.Data[i][j]
+			ppm.Data[i][j] = Pixel{v, v, v}
+		}
+	}
+	return ppm
+}
+
+// ToPGM converts the PBM image to a PGM image with a max value of 255,
+// where set pixels are 255 and unset pixels are 0.
+func (pbm *PBM) ToPGM() *PGM{
+	pgm := newPGM(pbm.Width, pbm.Height, 255)
+	for i := 0; i < pbm.Height; i++ {
+		for j := 0; j < pbm.Width; j++ {
+			if pbm.Data[i][j] {
+				pgm.Data[i][j] = 255
+			}
+		}
+	}
+	return pgm
+}
+
+// ToPPM converts the PBM image to a PPM image with a max value of 255,
+// using foreground for set pixels and background for unset pixels.
+func (pbm *PBM) ToPPM(foreground, background Pixel) *PPM{
+	ppm := newPPM(pbm.Width, pbm.Height, 255)
+	for i := 0; i < pbm.Height; i++ {
+		for j := 0; j < pbm.Width; j++ {
+			if pbm.Data[i][j] {
+				ppm.Data[i][j] = foreground
+			} else {
+				ppm.Data[i][j] = background
+			}
+		}
+	}
+	return ppm
+}
\ No newline at end of file
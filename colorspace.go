@@ -0,0 +1,430 @@
+package netpbm
+
+import (
+	"fmt"
+	"math"
+)
+
+// LinearRGB is a color in linear light sRGB, with channels in [0, 1].
+type LinearRGB struct {
+	R, G, B float64
+}
+
+// HSV is a color in the hue, saturation, value space.
+// H is in degrees [0, 360), S and V are in [0, 1].
+type HSV struct {
+	H, S, V float64
+}
+
+// HSL is a color in the hue, saturation, lightness space.
+// H is in degrees [0, 360), S and L are in [0, 1].
+type HSL struct {
+	H, S, L float64
+}
+
+// XYZ is a color in the CIE 1931 XYZ space with a D65 white point, Y in [0, 1].
+type XYZ struct {
+	X, Y, Z float64
+}
+
+// Lab is a color in the CIE L*a*b* space with a D65 white point, L in [0, 100].
+type Lab struct {
+	L, A, B float64
+}
+
+// LCh is the cylindrical form of Lab, H in degrees [0, 360).
+type LCh struct {
+	L, C, H float64
+}
+
+// YCbCr is a full range BT.601 color as used by JPEG, Y in [0, 1] and Cb, Cr in [-0.5, 0.5].
+type YCbCr struct {
+	Y, Cb, Cr float64
+}
+
+// D65 reference white.
+const (
+	whiteX = 0.95047
+	whiteY = 1.0
+	whiteZ = 1.08883
+)
+
+// normalized returns the channels of the pixel divided by max.
+func (p Pixel) normalized(max int) (r, g, b float64){
+	m := float64(max)
+	return float64(p.R) / m, float64(p.G) / m, float64(p.B) / m
+}
+
+// pixelFromNormalized returns the pixel with channels in [0, 1] scaled to max.
+func pixelFromNormalized(r, g, b float64, max int) Pixel{
+	m := float64(max)
+	return Pixel{clampToMax(r*m, max), clampToMax(g*m, max), clampToMax(b*m, max)}
+}
+
+// ToLinearRGB decodes the sRGB pixel, whose channels go from 0 to max, to linear light.
+func (p Pixel) ToLinearRGB(max int) LinearRGB{
+	r, g, b := p.normalized(max)
+	return LinearRGB{srgbToLinear(r), srgbToLinear(g), srgbToLinear(b)}
+}
+
+// ToPixel encodes the color to a sRGB pixel with channels from 0 to max.
+func (c LinearRGB) ToPixel(max int) Pixel{
+	return pixelFromNormalized(linearToSrgb(c.R), linearToSrgb(c.G), linearToSrgb(c.B), max)
+}
+
+// ToXYZ converts the linear sRGB color to XYZ.
+func (c LinearRGB) ToXYZ() XYZ{
+	return XYZ{
+		0.4124564*c.R + 0.3575761*c.G + 0.1804375*c.B,
+		0.2126729*c.R + 0.7151522*c.G + 0.0721750*c.B,
+		0.0193339*c.R + 0.1191920*c.G + 0.9503041*c.B,
+	}
+}
+
+// ToLinearRGB converts the XYZ color to linear sRGB.
+func (c XYZ) ToLinearRGB() LinearRGB{
+	return LinearRGB{
+		3.2404542*c.X - 1.5371385*c.Y - 0.4985314*c.Z,
+		-0.9692660*c.X + 1.8760108*c.Y + 0.0415560*c.Z,
+		0.0556434*c.X - 0.2040259*c.Y + 1.0572252*c.Z,
+	}
+}
+
+// ToLab converts the XYZ color to Lab.
+func (c XYZ) ToLab() Lab{
+	f := func(t float64) float64{
+		if t > 216.0/24389 {
+			return math.Cbrt(t)
+		}
+		return (24389.0/27*t + 16) / 116
+	}
+	fx, fy, fz := f(c.X/whiteX), f(c.Y/whiteY), f(c.Z/whiteZ)
+	return Lab{116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)}
+}
+
+// ToXYZ converts the Lab color to XYZ.
+func (c Lab) ToXYZ() XYZ{
+	finv := func(t float64) float64{
+		if t*t*t > 216.0/24389 {
+			return t * t * t
+		}
+		return (116*t - 16) * 27 / 24389
+	}
+	fy := (c.L + 16) / 116
+	fx := fy + c.A/500
+	fz := fy - c.B/200
+	return XYZ{finv(fx) * whiteX, finv(fy) * whiteY, finv(fz) * whiteZ}
+}
+
+// ToLCh converts the Lab color to LCh.
+func (c Lab) ToLCh() LCh{
+	h := math.Atan2(c.B, c.A) * 180 / math.Pi
+	if h < 0 {
+		h += 360
+	}
+	return LCh{c.L, math.Hypot(c.A, c.B), h}
+}
+
+// ToLab converts the LCh color to Lab.
+func (c LCh) ToLab() Lab{
+	h := c.H * math.Pi / 180
+	return Lab{c.L, c.C * math.Cos(h), c.C * math.Sin(h)}
+}
+
+// ToXYZ converts the pixel, whose channels go from 0 to max, to XYZ.
+func (p Pixel) ToXYZ(max int) XYZ{
+	return p.ToLinearRGB(max).ToXYZ()
+}
+
+// ToPixel converts the XYZ color to a pixel with channels from 0 to max.
+func (c XYZ) ToPixel(max int) Pixel{
+	return c.ToLinearRGB().ToPixel(max)
+}
+
+// ToLab converts the pixel, whose channels go from 0 to max, to Lab.
+func (p Pixel) ToLab(max int) Lab{
+	return p.ToXYZ(max).ToLab()
+}
+
+// ToPixel converts the Lab color to a pixel with channels from 0 to max.
+func (c Lab) ToPixel(max int) Pixel{
+	return c.ToXYZ().ToPixel(max)
+}
+
+// ToLCh converts the pixel, whose channels go from 0 to max, to LCh.
+func (p Pixel) ToLCh(max int) LCh{
+	return p.ToLab(max).ToLCh()
+}
+
+// ToPixel converts the LCh color to a pixel with channels from 0 to max.
+func (c LCh) ToPixel(max int) Pixel{
+	return c.ToLab().ToPixel(max)
+}
+
+// hueChroma returns the hue in degrees, and the largest and smallest channels.
+func hueChroma(r, g, b float64) (h, hi, lo float64){
+	hi = math.Max(r, math.Max(g, b))
+	lo = math.Min(r, math.Min(g, b))
+	d := hi - lo
+	switch {
+	case d == 0:
+		h = 0
+	case hi == r:
+		h = 60 * math.Mod((g-b)/d, 6)
+	case hi == g:
+		h = 60 * ((b-r)/d + 2)
+	default:
+		h = 60 * ((r-g)/d + 4)
+	}
+	if h < 0 {
+		h += 360
+	}
+	return h, hi, lo
+}
+
+// hueToRGB returns the normalized RGB of a hue with the given chroma and offset.
+func hueToRGB(h, chroma, m float64) (r, g, b float64){
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+	x := chroma * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	switch {
+	case h < 60:
+		r, g, b = chroma, x, 0
+	case h < 120:
+		r, g, b = x, chroma, 0
+	case h < 180:
+		r, g, b = 0, chroma, x
+	case h < 240:
+		r, g, b = 0, x, chroma
+	case h < 300:
+		r, g, b = x, 0, chroma
+	default:
+		r, g, b = chroma, 0, x
+	}
+	return r + m, g + m, b + m
+}
+
+// ToHSV converts the pixel, whose channels go from 0 to max, to HSV.
+func (p Pixel) ToHSV(max int) HSV{
+	h, hi, lo := hueChroma(p.normalized(max))
+	s := 0.0
+	if hi > 0 {
+		s = (hi - lo) / hi
+	}
+	return HSV{h, s, hi}
+}
+
+// ToPixel converts the HSV color to a pixel with channels from 0 to max.
+func (c HSV) ToPixel(max int) Pixel{
+	chroma := c.V * c.S
+	r, g, b := hueToRGB(c.H, chroma, c.V-chroma)
+	return pixelFromNormalized(r, g, b, max)
+}
+
+// ToHSL converts the pixel, whose channels go from 0 to max, to HSL.
+func (p Pixel) ToHSL(max int) HSL{
+	h, hi, lo := hueChroma(p.normalized(max))
+	l := (hi + lo) / 2
+	s := 0.0
+	if hi != lo {
+		s = (hi - lo) / (1 - math.Abs(2*l-1))
+	}
+	return HSL{h, s, l}
+}
+
+// ToPixel converts the HSL color to a pixel with channels from 0 to max.
+func (c HSL) ToPixel(max int) Pixel{
+	chroma := (1 - math.Abs(2*c.L-1)) * c.S
+	r, g, b := hueToRGB(c.H, chroma, c.L-chroma/2)
+	return pixelFromNormalized(r, g, b, max)
+}
+
+// ToYCbCr converts the pixel, whose channels go from 0 to max, to YCbCr.
+func (p Pixel) ToYCbCr(max int) YCbCr{
+	r, g, b := p.normalized(max)
+	y := 0.299*r + 0.587*g + 0.114*b
+	return YCbCr{y, (b - y) / 1.772, (r - y) / 1.402}
+}
+
+// ToPixel converts the YCbCr color to a pixel with channels from 0 to max.
+func (c YCbCr) ToPixel(max int) Pixel{
+	r := c.Y + 1.402*c.Cr
+	g := c.Y - 0.344136*c.Cb - 0.714136*c.Cr
+	b := c.Y + 1.772*c.Cb
+	return pixelFromNormalized(r, g, b, max)
+}
+
+// DeltaE76 returns the CIE 1976 color difference, the Euclidean distance in Lab.
+func DeltaE76(a, b Lab) float64{
+	return math.Sqrt((a.L-b.L)*(a.L-b.L) + (a.A-b.A)*(a.A-b.A) + (a.B-b.B)*(a.B-b.B))
+}
+
+// DeltaE2000 returns the CIEDE2000 color difference.
+func DeltaE2000(a, b Lab) float64{
+	rad := math.Pi / 180
+	c1, c2 := math.Hypot(a.A, a.B), math.Hypot(b.A, b.B)
+	cMean := (c1 + c2) / 2
+	c7 := math.Pow(cMean, 7)
+	g := 0.5 * (1 - math.Sqrt(c7/(c7+math.Pow(25, 7))))
+	a1, a2 := (1+g)*a.A, (1+g)*b.A
+	c1p, c2p := math.Hypot(a1, a.B), math.Hypot(a2, b.B)
+	hue := func(b, a float64) float64{
+		if a == 0 && b == 0 {
+			return 0
+		}
+		h := math.Atan2(b, a) / rad
+		if h < 0 {
+			h += 360
+		}
+		return h
+	}
+	h1p, h2p := hue(a.B, a1), hue(b.B, a2)
+
+	dL := b.L - a.L
+	dC := c2p - c1p
+	dh := 0.0
+	if c1p*c2p != 0 {
+		dh = h2p - h1p
+		if dh > 180 {
+			dh -= 360
+		} else if dh < -180 {
+			dh += 360
+		}
+	}
+	dH := 2 * math.Sqrt(c1p*c2p) * math.Sin(dh/2*rad)
+
+	lMean := (a.L + b.L) / 2
+	cpMean := (c1p + c2p) / 2
+	hMean := h1p + h2p
+	if c1p*c2p != 0 {
+		if math.Abs(h1p-h2p) <= 180 {
+			hMean /= 2
+		} else if hMean < 360 {
+			hMean = (hMean + 360) / 2
+		} else {
+			hMean = (hMean - 360) / 2
+		}
+	}
+	t := 1 - 0.17*math.Cos((hMean-30)*rad) + 0.24*math.Cos(2*hMean*rad) +
+		0.32*math.Cos((3*hMean+6)*rad) - 0.20*math.Cos((4*hMean-63)*rad)
+	dTheta := 30 * math.Exp(-((hMean-275)/25)*((hMean-275)/25))
+	cp7 := math.Pow(cpMean, 7)
+	rc := 2 * math.Sqrt(cp7/(cp7+math.Pow(25, 7)))
+	sl := 1 + 0.015*(lMean-50)*(lMean-50)/math.Sqrt(20+(lMean-50)*(lMean-50))
+	sc := 1 + 0.045*cpMean
+	sh := 1 + 0.015*cpMean*t
+	rt := -math.Sin(2*dTheta*rad) * rc
+
+	return math.Sqrt((dL/sl)*(dL/sl) + (dC/sc)*(dC/sc) + (dH/sh)*(dH/sh) + rt*(dC/sc)*(dH/sh))
+}
+
+// ColorSpace identifies the space of the planes of a PlanarImage.
+type ColorSpace int
+
+const (
+	// SpaceRGB holds sRGB channels in [0, 1].
+	SpaceRGB ColorSpace = iota
+	// SpaceLinearRGB holds linear light sRGB channels in [0, 1].
+	SpaceLinearRGB
+	// SpaceHSV holds H, S, V planes.
+	SpaceHSV
+	// SpaceHSL holds H, S, L planes.
+	SpaceHSL
+	// SpaceXYZ holds X, Y, Z planes.
+	SpaceXYZ
+	// SpaceLab holds L, a, b planes.
+	SpaceLab
+	// SpaceLCh holds L, C, h planes.
+	SpaceLCh
+	// SpaceYCbCr holds Y, Cb, Cr planes.
+	SpaceYCbCr
+)
+
+// PlanarImage is an image stored as three float64 planes in a given color space,
+// each plane holding the pixels row by row.
+type PlanarImage struct {
+	Planes        [3][]float64
+	Width, Height int
+	Space         ColorSpace
+}
+
+// ToPlanar converts the PPM image to three planes in the given color space.
+func (ppm *PPM) ToPlanar(space ColorSpace) *PlanarImage{
+	img := PlanarImage{Width: ppm.Width, Height: ppm.Height, Space: space}
+	for c := range img.Planes {
+		img.Planes[c] = make([]float64, ppm.Width*ppm.Height)
+	}
+	for i := 0; i < ppm.Height; i++ {
+		for j := 0; j < ppm.Width; j++ {
+			p := ppm.Data[i][j]
+			var v0, v1, v2 float64
+			switch space {
+			case SpaceLinearRGB:
+				c := p.ToLinearRGB(ppm.Max)
+				v0, v1, v2 = c.R, c.G, c.B
+			case SpaceHSV:
+				c := p.ToHSV(ppm.Max)
+				v0, v1, v2 = c.H, c.S, c.V
+			case SpaceHSL:
+				c := p.ToHSL(ppm.Max)
+				v0, v1, v2 = c.H, c.S, c.L
+			case SpaceXYZ:
+				c := p.ToXYZ(ppm.Max)
+				v0, v1, v2 = c.X, c.Y, c.Z
+			case SpaceLab:
+				c := p.ToLab(ppm.Max)
+				v0, v1, v2 = c.L, c.A, c.B
+			case SpaceLCh:
+				c := p.ToLCh(ppm.Max)
+				v0, v1, v2 = c.L, c.C, c.H
+			case SpaceYCbCr:
+				c := p.ToYCbCr(ppm.Max)
+				v0, v1, v2 = c.Y, c.Cb, c.Cr
+			default:
+				v0, v1, v2 = p.normalized(ppm.Max)
+			}
+			k := i*ppm.Width + j
+			img.Planes[0][k], img.Planes[1][k], img.Planes[2][k] = v0, v1, v2
+		}
+	}
+	return &img
+}
+
+// ToPPM converts the planar image back to a PPM image with the given max value.
+func (img *PlanarImage) ToPPM(max int) (*PPM, error){
+	for c, plane := range img.Planes {
+		if len(plane) != img.Width*img.Height {
+			return nil, fmt.Errorf("invalid planar image: plane %d has %d values, expected %d", c, len(plane), img.Width*img.Height)
+		}
+	}
+	ppm := newPPM(img.Width, img.Height, max)
+	for i := 0; i < img.Height; i++ {
+		for j := 0; j < img.Width; j++ {
+			k := i*img.Width + j
+			v0, v1, v2 := img.Planes[0][k], img.Planes[1][k], img.Planes[2][k]
+			var p Pixel
+			switch img.Space {
+			case SpaceLinearRGB:
+				p = LinearRGB{v0, v1, v2}.ToPixel(max)
+			case SpaceHSV:
+				p = HSV{v0, v1, v2}.ToPixel(max)
+			case SpaceHSL:
+				p = HSL{v0, v1, v2}.ToPixel(max)
+			case SpaceXYZ:
+				p = XYZ{v0, v1, v2}.ToPixel(max)
+			case SpaceLab:
+				p = Lab{v0, v1, v2}.ToPixel(max)
+			case SpaceLCh:
+				p = LCh{v0, v1, v2}.ToPixel(max)
+			case SpaceYCbCr:
+				p = YCbCr{v0, v1, v2}.ToPixel(max)
+			default:
+				p = pixelFromNormalized(v0, v1, v2, max)
+			}
+			ppm.Data[i][j] = p
+		}
+	}
+	return ppm, nil
+}
\ No newline at end of file
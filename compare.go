@@ -0,0 +1,192 @@
+package netpbm
+
+import (
+	"fmt"
+	"math"
+)
+
+// Comparison holds the metrics computed by ComparePBM, ComparePGM and ComparePPM.
+// MSE, MAE and MaxError are in pixel values and averaged over channels;
+// SSIM and MSSSIM are the mean of the channel indices.
+type Comparison struct {
+	MSE, PSNR, MAE  float64
+	MaxError        int
+	SSIM, MSSSIM    float64
+	DifferentPixels int
+}
+
+// ComparePBM compares two PBM images, using 0 and 1 as pixel values.
+func ComparePBM(a, b *PBM) (*Comparison, error){
+	if a.Width != b.Width || a.Height != b.Height {
+		return nil, fmt.Errorf("image sizes differ: %dx%d and %dx%d", a.Width, a.Height, b.Width, b.Height)
+	}
+	plane := func(pbm *PBM) []float64{
+		p := make([]float64, pbm.Width*pbm.Height)
+		for i := 0; i < pbm.Height; i++ {
+			for j := 0; j < pbm.Width; j++ {
+				if pbm.Data[i][j] {
+					p[i*pbm.Width+j] = 1
+				}
+			}
+		}
+		return p
+	}
+	return compare([][]float64{plane(a)}, [][]float64{plane(b)}, a.Width, a.Height, 1), nil
+}
+
+// ComparePGM compares two PGM images. PSNR and SSIM use the Max of a.
+func ComparePGM(a, b *PGM) (*Comparison, error){
+	if a.Width != b.Width || a.Height != b.Height {
+		return nil, fmt.Errorf("image sizes differ: %dx%d and %dx%d", a.Width, a.Height, b.Width, b.Height)
+	}
+	return compare([][]float64{a.plane()}, [][]float64{b.plane()}, a.Width, a.Height, a.Max), nil
+}
+
+// ComparePPM compares two PPM images. PSNR and SSIM use the Max of a.
+func ComparePPM(a, b *PPM) (*Comparison, error){
+	if a.Width != b.Width || a.Height != b.Height {
+		return nil, fmt.Errorf("image sizes differ: %dx%d and %dx%d", a.Width, a.Height, b.Width, b.Height)
+	}
+	ar, ag, ab := a.planes()
+	br, bg, bb := b.planes()
+	return compare([][]float64{ar, ag, ab}, [][]float64{br, bg, bb}, a.Width, a.Height, a.Max), nil
+}
+
+// compare computes the metrics of two images given as lists of channel planes.
+func compare(a, b [][]float64, width, height, max int) *Comparison{
+	var c Comparison
+	n := width * height
+	if n == 0 {
+		c.PSNR, c.SSIM, c.MSSSIM = math.Inf(1), 1, 1
+		return &c
+	}
+	different := make([]bool, n)
+	sumSq, sumAbs := 0.0, 0.0
+	for ch := range a {
+		for i := 0; i < n; i++ {
+			d := math.Abs(a[ch][i] - b[ch][i])
+			sumSq += d * d
+			sumAbs += d
+			if d > 0 {
+				different[i] = true
+				c.MaxError = int(math.Max(float64(c.MaxError), d))
+			}
+		}
+		index, _ := ssim(a[ch], b[ch], width, height, max)
+		c.SSIM += index
+		c.MSSSIM += msSSIM(a[ch], b[ch], width, height, max)
+	}
+	for _, d := range different {
+		if d {
+			c.DifferentPixels++
+		}
+	}
+	channels := float64(len(a))
+	c.MSE = sumSq / (float64(n) * channels)
+	c.MAE = sumAbs / (float64(n) * channels)
+	c.SSIM /= channels
+	c.MSSSIM /= channels
+	if c.MSE == 0 {
+		c.PSNR = math.Inf(1)
+	} else {
+		c.PSNR = 10 * math.Log10(float64(max)*float64(max)/c.MSE)
+	}
+	return &c
+}
+
+// ssim returns the structural similarity index of two channels and its mean
+// contrast-structure term, computed with an 11x11 Gaussian window of standard deviation 1.5.
+func ssim(a, b []float64, width, height, max int) (index, contrast float64){
+	c1 := (0.01 * float64(max)) * (0.01 * float64(max))
+	c2 := (0.03 * float64(max)) * (0.03 * float64(max))
+	kernel := GaussianKernel(1.5)
+	blur := func(p []float64) []float64{
+		return convolvePlane(p, width, height, kernel, EdgeMirror)
+	}
+	aa, bb, ab := make([]float64, len(a)), make([]float64, len(a)), make([]float64, len(a))
+	for i := range a {
+		aa[i], bb[i], ab[i] = a[i]*a[i], b[i]*b[i], a[i]*b[i]
+	}
+	muA, muB := blur(a), blur(b)
+	sigmaAA, sigmaBB, sigmaAB := blur(aa), blur(bb), blur(ab)
+	for i := range a {
+		va := sigmaAA[i] - muA[i]*muA[i]
+		vb := sigmaBB[i] - muB[i]*muB[i]
+		cov := sigmaAB[i] - muA[i]*muB[i]
+		cs := (2*cov + c2) / (va + vb + c2)
+		index += (2*muA[i]*muB[i] + c1) / (muA[i]*muA[i] + muB[i]*muB[i] + c1) * cs
+		contrast += cs
+	}
+	return index / float64(len(a)), contrast / float64(len(a))
+}
+
+// msSSIMWeights are the scale weights of Wang et al. for 5 scale MS-SSIM.
+var msSSIMWeights = []float64{0.0448, 0.2856, 0.3001, 0.2363, 0.1333}
+
+// msSSIM returns the multi-scale structural similarity index of two channels.
+// Images too small for 5 scales use as many as possible with renormalized weights.
+func msSSIM(a, b []float64, width, height, max int) float64{
+	scales := 1
+	for w, h := width, height; scales < len(msSSIMWeights) && w >= 22 && h >= 22; w, h = w/2, h/2 {
+		scales++
+	}
+	weights := msSSIMWeights[len(msSSIMWeights)-scales:]
+	totalWeight := 0.0
+	for _, w := range weights {
+		totalWeight += w
+	}
+	result := 1.0
+	for s := 0; s < scales; s++ {
+		weight := weights[s] / totalWeight
+		index, contrast := ssim(a, b, width, height, max)
+		if s == scales-1 {
+			result *= math.Pow(math.Max(index, 0), weight)
+			break
+		}
+		result *= math.Pow(math.Max(contrast, 0), weight)
+		a, _, _ = downsample(a, width, height)
+		b, width, height = downsample(b, width, height)
+	}
+	return result
+}
+
+// downsample halves a channel by averaging 2x2 blocks.
+func downsample(p []float64, width, height int) ([]float64, int, int){
+	w, h := width/2, height/2
+	out := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			out[y*w+x] = (p[2*y*width+2*x] + p[2*y*width+2*x+1] + p[(2*y+1)*width+2*x] + p[(2*y+1)*width+2*x+1]) / 4
+		}
+	}
+	return out, w, h
+}
+
+// DiffImage returns a PPM image showing a dimmed gray version of a, where the pixels
+// whose largest channel difference with b is above threshold are drawn with the
+// highlight color, from half to full brightness depending on the difference.
+func DiffImage(a, b *PPM, threshold int, highlight Pixel) (*PPM, error){
+	if a.Width != b.Width || a.Height != b.Height {
+		return nil, fmt.Errorf("image sizes differ: %dx%d and %dx%d", a.Width, a.Height, b.Width, b.Height)
+	}
+	diff := newPPM(a.Width, a.Height, a.Max)
+	gray := a.ToPGM()
+	for i := 0; i < a.Height; i++ {
+		for j := 0; j < a.Width; j++ {
+			p, q := a.Data[i][j], b.Data[i][j]
+			d := max(absInt(int(p.R)-int(q.R)), absInt(int(p.G)-int(q.G)), absInt(int(p.B)-int(q.B)))
+			if d > threshold {
+				f := 0.5 + 0.5*float64(d)/float64(max(a.Max, 1))
+				diff.Data[i][j] = Pixel{
+					clampToMax(float64(highlight.R)*f, a.Max),
+					clampToMax(float64(highlight.G)*f, a.Max),
+					clampToMax(float64(highlight.B)*f, a.Max),
+				}
+			} else {
+				v := gray.Data[i][j] / 3
+				diff.Data[i][j] = Pixel{v, v, v}
+			}
+		}
+	}
+	return diff, nil
+}
\ No newline at end of file
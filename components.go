@@ -0,0 +1,169 @@
+package netpbm
+
+import "math"
+
+// Connectivity selects which neighbours of a pixel are connected to it.
+type Connectivity int
+
+const (
+	// Connectivity4 connects pixels sharing an edge.
+	Connectivity4 Connectivity = 4
+	// Connectivity8 connects pixels sharing an edge or a corner.
+	Connectivity8 Connectivity = 8
+)
+
+// neighbours returns the offsets of the connected neighbours.
+func (connectivity Connectivity) neighbours() []Point{
+	if connectivity == Connectivity4 {
+		return []Point{{1, 0}, {0, 1}, {-1, 0}, {0, -1}}
+	}
+	return []Point{{1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}, {1, -1}}
+}
+
+// LabelImage associates a label with each pixel; 0 is the background.
+type LabelImage struct {
+	Data          [][]int
+	Width, Height int
+}
+
+// newLabelImage returns a label image of the given size with every pixel set to 0.
+func newLabelImage(width, height int) *LabelImage{
+	data := make([][]int, height)
+	for i := range data {
+		data[i] = make([]int, width)
+	}
+	return &LabelImage{Data: data, Width: width, Height: height}
+}
+
+// Component holds the statistics of a connected component.
+// Min and Max are the inclusive corners of its bounding box, and Perimeter is the
+// number of pixel edges between the component and the rest of the image.
+type Component struct {
+	Label                int
+	Area                 int
+	Min, Max             Point
+	CentroidX, CentroidY float64
+	Perimeter            int
+}
+
+// ConnectedComponents labels the connected regions of set pixels of the PBM image,
+// from 1 in scan order, and returns their statistics indexed by label-1.
+func ConnectedComponents(pbm *PBM, connectivity Connectivity) (*LabelImage, []Component){
+	labels := newLabelImage(pbm.Width, pbm.Height)
+	neighbours := connectivity.neighbours()
+	var components []Component
+	var stack []Point
+	for y := 0; y < pbm.Height; y++ {
+		for x := 0; x < pbm.Width; x++ {
+			if !pbm.Data[y][x] || labels.Data[y][x] != 0 {
+				continue
+			}
+			label := len(components) + 1
+			c := Component{Label: label, Min: Point{x, y}, Max: Point{x, y}}
+			sumX, sumY := 0, 0
+			labels.Data[y][x] = label
+			stack = append(stack[:0], Point{x, y})
+			for len(stack) > 0 {
+				p := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				c.Area++
+				sumX += p.X
+				sumY += p.Y
+				c.Min = Point{min(c.Min.X, p.X), min(c.Min.Y, p.Y)}
+				c.Max = Point{max(c.Max.X, p.X), max(c.Max.Y, p.Y)}
+				for _, d := range []Point{{1, 0}, {0, 1}, {-1, 0}, {0, -1}} {
+					nx, ny := p.X+d.X, p.Y+d.Y
+					if nx < 0 || ny < 0 || nx >= pbm.Width || ny >= pbm.Height || !pbm.Data[ny][nx] {
+						c.Perimeter++
+					}
+				}
+				for _, d := range neighbours {
+					nx, ny := p.X+d.X, p.Y+d.Y
+					if nx < 0 || ny < 0 || nx >= pbm.Width || ny >= pbm.Height {
+						continue
+					}
+					if pbm.Data[ny][nx] && labels.Data[ny][nx] == 0 {
+						labels.Data[ny][nx] = label
+						stack = append(stack, Point{nx, ny})
+					}
+				}
+			}
+			c.CentroidX = float64(sumX) / float64(c.Area)
+			c.CentroidY = float64(sumY) / float64(c.Area)
+			components = append(components, c)
+		}
+	}
+	return labels, components
+}
+
+// FilterByArea removes from the label image the components whose area is not
+// between minArea and maxArea (0 means no upper bound), and returns the others.
+// Labels are not renumbered.
+func (labels *LabelImage) FilterByArea(components []Component, minArea, maxArea int) []Component{
+	removed := make(map[int]bool)
+	var kept []Component
+	for _, c := range components {
+		if c.Area < minArea || (maxArea > 0 && c.Area > maxArea) {
+			removed[c.Label] = true
+		} else {
+			kept = append(kept, c)
+		}
+	}
+	for i := 0; i < labels.Height; i++ {
+		for j := 0; j < labels.Width; j++ {
+			if removed[labels.Data[i][j]] {
+				labels.Data[i][j] = 0
+			}
+		}
+	}
+	return kept
+}
+
+// Mask returns a PBM image where the pixels with the given label are set.
+func (labels *LabelImage) Mask(label int) *PBM{
+	pbm := newPBM(labels.Width, labels.Height)
+	for i := 0; i < labels.Height; i++ {
+		for j := 0; j < labels.Width; j++ {
+			pbm.Data[i][j] = labels.Data[i][j] == label
+		}
+	}
+	return pbm
+}
+
+// Masks returns a PBM mask for each label from 1 to the largest label, indexed by label-1.
+func (labels *LabelImage) Masks() []*PBM{
+	var masks []*PBM
+	for i := 0; i < labels.Height; i++ {
+		for j := 0; j < labels.Width; j++ {
+			label := labels.Data[i][j]
+			if label <= 0 {
+				continue
+			}
+			for len(masks) < label {
+				masks = append(masks, newPBM(labels.Width, labels.Height))
+			}
+			masks[label-1].Data[i][j] = true
+		}
+	}
+	return masks
+}
+
+// labelColor returns a distinct color for a label, spreading hues with the golden ratio.
+func labelColor(label int) Pixel{
+	hue := math.Mod(float64(label)*0.618033988749895, 1) * 360
+	return HSV{hue, 0.65 + 0.35*float64(label%2), 0.95}.ToPixel(255)
+}
+
+// ToPPM returns a PPM image with a max value of 255 where each label has its own
+// color and the background is black.
+func (labels *LabelImage) ToPPM() *PPM{
+	ppm := newPPM(labels.Width, labels.Height, 255)
+	for i := 0; i < labels.Height; i++ {
+		for j := 0; j < labels.Width; j++ {
+			if label := labels.Data[i][j]; label > 0 {
+				ppm.Data[i][j] = labelColor(label)
+			}
+		}
+	}
+	return ppm
+}
\ No newline at end of file
@@ -0,0 +1,154 @@
+package netpbm
+
+import (
+	"fmt"
+	"math"
+)
+
+// CompositeOperator is a Porter-Duff operator combining a source with a destination.
+type CompositeOperator int
+
+const (
+	// OpOver draws the source over the destination.
+	OpOver CompositeOperator = iota
+	// OpIn keeps the source where the destination is opaque.
+	OpIn
+	// OpOut keeps the source where the destination is transparent.
+	OpOut
+	// OpAtop draws the source over the destination, only where the destination is opaque.
+	OpAtop
+	// OpXor keeps the source and the destination where they do not overlap.
+	OpXor
+)
+
+// BlendMode selects how the source and destination colors are mixed where they overlap.
+type BlendMode int
+
+const (
+	BlendNormal BlendMode = iota
+	BlendMultiply
+	BlendScreen
+	BlendOverlay
+	BlendDarken
+	BlendLighten
+	BlendDifference
+	BlendSoftLight
+)
+
+// CompositeOptions configures PPM.Composite.
+type CompositeOptions struct {
+	Operator CompositeOperator
+	Blend    BlendMode
+	// SourceAlpha is the alpha channel, or mask, of the source: 0 is transparent and Max
+	// opaque. It must have the size of the source; nil means opaque. A PBM mask can be
+	// used through PBM.ToPGM.
+	SourceAlpha *PGM
+	// DestinationAlpha is the alpha channel of the destination, updated with the result.
+	// It must have the size of the destination; nil means opaque, in which case the
+	// result is flattened onto black.
+	DestinationAlpha *PGM
+}
+
+// blend returns the blended value of the backdrop b and source s, both in [0, 1].
+func blend(mode BlendMode, b, s float64) float64{
+	switch mode {
+	case BlendMultiply:
+		return b * s
+	case BlendScreen:
+		return b + s - b*s
+	case BlendOverlay:
+		return hardLight(s, b)
+	case BlendDarken:
+		return math.Min(b, s)
+	case BlendLighten:
+		return math.Max(b, s)
+	case BlendDifference:
+		return math.Abs(b - s)
+	case BlendSoftLight:
+		if s <= 0.5 {
+			return b - (1-2*s)*b*(1-b)
+		}
+		var d float64
+		if b <= 0.25 {
+			d = ((16*b-12)*b + 4) * b
+		} else {
+			d = math.Sqrt(b)
+		}
+		return b + (2*s-1)*(d-b)
+	default:
+		return s
+	}
+}
+
+// hardLight returns the hard light blend of the backdrop b and source s.
+// Overlay is hard light with the layers swapped.
+func hardLight(b, s float64) float64{
+	if s <= 0.5 {
+		return b * 2 * s
+	}
+	return blend(BlendScreen, b, 2*s-1)
+}
+
+// porterDuff returns the fractions of the source and destination kept by the operator.
+func porterDuff(op CompositeOperator, as, ab float64) (fa, fb float64){
+	switch op {
+	case OpIn:
+		return ab, 0
+	case OpOut:
+		return 1 - ab, 0
+	case OpAtop:
+		return ab, 1 - as
+	case OpXor:
+		return 1 - ab, 1 - as
+	default:
+		return 1, 1 - as
+	}
+}
+
+// Composite combines the source image, placed with its top left corner at offset, with
+// the PPM image. opacity (0 to 1) scales the source alpha. Only the pixels covered by
+// the source are changed.
+func (ppm *PPM) Composite(src *PPM, offset Point, opacity float64, options CompositeOptions) error{
+	if a := options.SourceAlpha; a != nil && (a.Width != src.Width || a.Height != src.Height) {
+		return fmt.Errorf("source alpha is %dx%d, expected %dx%d", a.Width, a.Height, src.Width, src.Height)
+	}
+	if a := options.DestinationAlpha; a != nil && (a.Width != ppm.Width || a.Height != ppm.Height) {
+		return fmt.Errorf("destination alpha is %dx%d, expected %dx%d", a.Width, a.Height, ppm.Width, ppm.Height)
+	}
+	opacity = math.Max(0, math.Min(1, opacity))
+	srcMax, dstMax := float64(src.Max), float64(ppm.Max)
+	for y := max(0, offset.Y); y < min(ppm.Height, offset.Y+src.Height); y++ {
+		for x := max(0, offset.X); x < min(ppm.Width, offset.X+src.Width); x++ {
+			sx, sy := x-offset.X, y-offset.Y
+			as := opacity
+			if a := options.SourceAlpha; a != nil {
+				as *= float64(a.Data[sy][sx]) / float64(a.Max)
+			}
+			ab := 1.0
+			if a := options.DestinationAlpha; a != nil {
+				ab = float64(a.Data[y][x]) / float64(a.Max)
+			}
+			fa, fb := porterDuff(options.Operator, as, ab)
+			ao := as*fa + ab*fb
+
+			s, b := src.Data[sy][sx], ppm.Data[y][x]
+			channel := func(cs, cb uint8) uint8{
+				vs, vb := float64(cs)/srcMax, float64(cb)/dstMax
+				vs = (1-ab)*vs + ab*blend(options.Blend, vb, vs)
+				co := vs*as*fa + vb*ab*fb
+				if options.DestinationAlpha != nil {
+					if ao == 0 {
+						return 0
+					}
+					co /= ao
+				}
+				return clampToMax(co*dstMax, ppm.Max)
+			}
+			ppm.Data[y][x] = Pixel{channel(s.R, b.R), channel(s.G, b.G), channel(s.B, b.B)}
+			if a := options.DestinationAlpha; a != nil {
+				a.Data[y][x] = clampToMax(ao*float64(a.Max), a.Max)
+			}
+		}
+	}
+	return nil
+}
\ No newline at end of file
@@ -0,0 +1,246 @@
+package netpbm
+
+import (
+	"math"
+	"sort"
+)
+
+// Contour is a closed border of a region of set pixels, as a chain of 8-connected
+// points that can be drawn with PPM.DrawPolygon.
+// Hole is true for the border of a hole inside a region, and Parent is the index of
+// the enclosing contour in the slice returned by FindContours, or -1.
+type Contour struct {
+	Points []Point
+	Hole   bool
+	Parent int
+}
+
+// contourDirections lists the 8 neighbours in clockwise order starting east, as (dy, dx).
+var contourDirections = [8][2]int{{0, 1}, {1, 1}, {1, 0}, {1, -1}, {0, -1}, {-1, -1}, {-1, 0}, {-1, 1}}
+
+// contourDirection returns the index in contourDirections of the step from (y0, x0) to (y1, x1).
+func contourDirection(y0, x0, y1, x1 int) int{
+	for d, step := range contourDirections {
+		if step[0] == y1-y0 && step[1] == x1-x0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// FindContours returns the outer borders and hole borders of the set regions of the
+// PBM image (8-connected), using the Suzuki-Abe border following algorithm.
+func FindContours(pbm *PBM) []Contour{
+	// Image with a one pixel frame of zeros; border numbers are written in place
+	width, height := pbm.Width+2, pbm.Height+2
+	f := make([][]int, height)
+	for i := range f {
+		f[i] = make([]int, width)
+	}
+	for i := 0; i < pbm.Height; i++ {
+		for j := 0; j < pbm.Width; j++ {
+			if pbm.Data[i][j] {
+				f[i+1][j+1] = 1
+			}
+		}
+	}
+
+	// Border 1 is the frame; contours[k] is border k+2
+	var contours []Contour
+	holeOf := func(border int) bool{
+		if border == 1 {
+			return true
+		}
+		return contours[border-2].Hole
+	}
+	parentOf := func(border int) int{
+		if border == 1 {
+			return -1
+		}
+		return contours[border-2].Parent
+	}
+
+	nbd := 1
+	for i := 1; i < height-1; i++ {
+		lnbd := 1
+		for j := 1; j < width-1; j++ {
+			if f[i][j] == 0 {
+				continue
+			}
+			var i2, j2 int
+			var hole bool
+			if f[i][j] == 1 && f[i][j-1] == 0 {
+				i2, j2 = i, j-1
+			} else if f[i][j] >= 1 && f[i][j+1] == 0 {
+				i2, j2 = i, j+1
+				hole = true
+				if f[i][j] > 1 {
+					lnbd = f[i][j]
+				}
+			} else {
+				if f[i][j] != 1 {
+					lnbd = absInt(f[i][j])
+				}
+				continue
+			}
+
+			nbd++
+			contour := Contour{Hole: hole}
+			if hole == holeOf(lnbd) {
+				contour.Parent = parentOf(lnbd)
+			} else if lnbd == 1 {
+				contour.Parent = -1
+			} else {
+				contour.Parent = lnbd - 2
+			}
+
+			// Find the first set neighbour clockwise from (i2, j2)
+			start := contourDirection(i, j, i2, j2)
+			i1, j1, found := 0, 0, false
+			for k := 0; k < 8; k++ {
+				d := contourDirections[(start+k)%8]
+				if f[i+d[0]][j+d[1]] != 0 {
+					i1, j1, found = i+d[0], j+d[1], true
+					break
+				}
+			}
+			contour.Points = append(contour.Points, Point{j - 1, i - 1})
+			if !found {
+				f[i][j] = -nbd
+			} else {
+				i2, j2 = i1, j1
+				i3, j3 := i, j
+				for {
+					// Search counterclockwise from the element after (i2, j2)
+					from := contourDirection(i3, j3, i2, j2)
+					var i4, j4 int
+					eastZero := false
+					for k := 1; k <= 8; k++ {
+						dir := (from - k + 16) % 8
+						d := contourDirections[dir]
+						if f[i3+d[0]][j3+d[1]] != 0 {
+							i4, j4 = i3+d[0], j3+d[1]
+							break
+						}
+						if dir == 0 {
+							eastZero = true
+						}
+					}
+					if eastZero {
+						f[i3][j3] = -nbd
+					} else if f[i3][j3] == 1 {
+						f[i3][j3] = nbd
+					}
+					if i4 == i && j4 == j && i3 == i1 && j3 == j1 {
+						break
+					}
+					i2, j2, i3, j3 = i3, j3, i4, j4
+					contour.Points = append(contour.Points, Point{j3 - 1, i3 - 1})
+				}
+			}
+			contours = append(contours, contour)
+			if f[i][j] != 1 {
+				lnbd = absInt(f[i][j])
+			}
+		}
+	}
+	return contours
+}
+
+// perpendicularDistance returns the distance from p to the line through a and b.
+func perpendicularDistance(p, a, b Point) float64{
+	dx, dy := float64(b.X-a.X), float64(b.Y-a.Y)
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return math.Hypot(float64(p.X-a.X), float64(p.Y-a.Y))
+	}
+	return math.Abs(dy*float64(p.X-a.X)-dx*float64(p.Y-a.Y)) / length
+}
+
+// douglasPeucker simplifies the open chain points[first..last] into keep.
+func douglasPeucker(points []Point, first, last int, epsilon float64, keep []bool){
+	best, bestDistance := -1, epsilon
+	for i := first + 1; i < last; i++ {
+		if d := perpendicularDistance(points[i], points[first], points[last]); d > bestDistance {
+			best, bestDistance = i, d
+		}
+	}
+	if best < 0 {
+		return
+	}
+	keep[best] = true
+	douglasPeucker(points, first, best, epsilon, keep)
+	douglasPeucker(points, best, last, epsilon, keep)
+}
+
+// SimplifyPolygon returns the points of the polygon, or open polyline if closed is
+// false, that the Douglas-Peucker algorithm keeps: every removed point is within
+// epsilon of the simplified shape.
+func SimplifyPolygon(points []Point, epsilon float64, closed bool) []Point{
+	if len(points) < 3 {
+		return append([]Point(nil), points...)
+	}
+	keep := make([]bool, len(points))
+	keep[0] = true
+	if closed {
+		// Split the polygon at the point farthest from the first one
+		far, farDistance := 0, -1.0
+		for i, p := range points {
+			if d := math.Hypot(float64(p.X-points[0].X), float64(p.Y-points[0].Y)); d > farDistance {
+				far, farDistance = i, d
+			}
+		}
+		keep[far] = true
+		ring := append(append([]Point(nil), points...), points[0])
+		ringKeep := make([]bool, len(ring))
+		douglasPeucker(ring, 0, far, epsilon, ringKeep)
+		douglasPeucker(ring, far, len(ring)-1, epsilon, ringKeep)
+		for i := range points {
+			keep[i] = keep[i] || ringKeep[i]
+		}
+	} else {
+		keep[len(points)-1] = true
+		douglasPeucker(points, 0, len(points)-1, epsilon, keep)
+	}
+	var simplified []Point
+	for i, p := range points {
+		if keep[i] {
+			simplified = append(simplified, p)
+		}
+	}
+	return simplified
+}
+
+// ConvexHull returns the convex hull of the points, without collinear points,
+// using Andrew's monotone chain algorithm.
+func ConvexHull(points []Point) []Point{
+	pts := append([]Point(nil), points...)
+	sort.Slice(pts, func(i, j int) bool{
+		if pts[i].X != pts[j].X {
+			return pts[i].X < pts[j].X
+		}
+		return pts[i].Y < pts[j].Y
+	})
+	if len(pts) < 3 {
+		return pts
+	}
+	cross := func(o, a, b Point) int{
+		return (a.X-o.X)*(b.Y-o.Y) - (a.Y-o.Y)*(b.X-o.X)
+	}
+	hull := make([]Point, 0, 2*len(pts))
+	for _, p := range pts {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	lower := len(hull) + 1
+	for i := len(pts) - 2; i >= 0; i-- {
+		p := pts[i]
+		for len(hull) >= lower && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	return hull[:len(hull)-1]
+}
\ No newline at end of file
@@ -0,0 +1,367 @@
+package netpbm
+
+import (
+	"fmt"
+	"math"
+)
+
+// EdgeMode selects how pixels outside the image are read during a convolution.
+type EdgeMode int
+
+const (
+	// EdgeClamp repeats the nearest border pixel.
+	EdgeClamp EdgeMode = iota
+	// EdgeMirror reflects the image around its border (without repeating the border pixel).
+	EdgeMirror
+	// EdgeWrap wraps around to the opposite side of the image.
+	EdgeWrap
+	// EdgeZero treats every pixel outside the image as 0.
+	EdgeZero
+)
+
+// Kernel is a convolution kernel with odd width and height.
+// Row and Column are only set for separable kernels, in which case
+// Data is their outer product and Convolve applies them in two passes.
+type Kernel struct {
+	Data          [][]float64
+	Width, Height int
+	Row, Column   []float64
+}
+
+// NewKernel creates a kernel from a rectangular matrix with odd dimensions.
+func NewKernel(data [][]float64) (*Kernel, error) {
+	if len(data) == 0 || len(data)%2 == 0 {
+		return nil, fmt.Errorf("invalid kernel: height must be odd")
+	}
+	width := len(data[0])
+	if width%2 == 0 {
+		return nil, fmt.Errorf("invalid kernel: width must be odd")
+	}
+	kernel := Kernel{Width: width, Height: len(data)}
+	kernel.Data = make([][]float64, kernel.Height)
+	for i, row := range data {
+		if len(row) != width {
+			return nil, fmt.Errorf("invalid kernel: row %d has %d values, expected %d", i, len(row), width)
+		}
+		kernel.Data[i] = append([]float64(nil), row...)
+	}
+	return &kernel, nil
+}
+
+// NewSeparableKernel creates a kernel that is the outer product of a column
+// (vertical) and a row (horizontal) vector, both of odd length.
+func NewSeparableKernel(row, column []float64) (*Kernel, error) {
+	if len(row)%2 == 0 || len(column)%2 == 0 {
+		return nil, fmt.Errorf("invalid kernel: row and column lengths must be odd")
+	}
+	kernel := Kernel{
+		Width:  len(row),
+		Height: len(column),
+		Row:    append([]float64(nil), row...),
+		Column: append([]float64(nil), column...),
+	}
+	kernel.Data = make([][]float64, kernel.Height)
+	for i := range kernel.Data {
+		kernel.Data[i] = make([]float64, kernel.Width)
+		for j := range kernel.Data[i] {
+			kernel.Data[i][j] = column[i] * row[j]
+		}
+	}
+	return &kernel, nil
+}
+
+// IsSeparable reports whether the kernel will be applied in two 1D passes.
+func (kernel *Kernel) IsSeparable() bool{
+	return len(kernel.Row) > 0 && len(kernel.Column) > 0
+}
+
+// GaussianKernel returns a normalized separable Gaussian kernel.
+// The radius is 3*sigma rounded up, which covers more than 99% of the weight.
+func GaussianKernel(sigma float64) *Kernel{
+	weights := gaussianWeights(sigma)
+	kernel, _ := NewSeparableKernel(weights, weights)
+	return kernel
+}
+
+// BoxKernel returns a normalized separable box kernel of size (2*radius+1)².
+func BoxKernel(radius int) *Kernel{
+	if radius < 0 {
+		radius = 0
+	}
+	weights := make([]float64, 2*radius+1)
+	for i := range weights {
+		weights[i] = 1 / float64(len(weights))
+	}
+	kernel, _ := NewSeparableKernel(weights, weights)
+	return kernel
+}
+
+// SharpenKernel returns the classic 3x3 sharpening kernel.
+func SharpenKernel() *Kernel{
+	kernel, _ := NewKernel([][]float64{
+		{0, -1, 0},
+		{-1, 5, -1},
+		{0, -1, 0},
+	})
+	return kernel
+}
+
+// gaussianWeights returns normalized 1D Gaussian weights for the given sigma.
+func gaussianWeights(sigma float64) []float64{
+	if sigma <= 0 {
+		return []float64{1}
+	}
+	radius := int(math.Ceil(3 * sigma))
+	weights := make([]float64, 2*radius+1)
+	sum := 0.0
+	for i := -radius; i <= radius; i++ {
+		weights[i+radius] = math.Exp(-float64(i*i) / (2 * sigma * sigma))
+		sum += weights[i+radius]
+	}
+	for i := range weights {
+		weights[i] /= sum
+	}
+	return weights
+}
+
+// edgeIndex maps a coordinate that may lie outside [0, n) back into the image.
+// It returns false when the pixel must be treated as 0 (EdgeZero).
+func edgeIndex(i, n int, mode EdgeMode) (int, bool){
+	if i >= 0 && i < n {
+		return i, true
+	}
+	switch mode {
+	case EdgeZero:
+		return 0, false
+	case EdgeWrap:
+		i %= n
+		if i < 0 {
+			i += n
+		}
+		return i, true
+	case EdgeMirror:
+		if n == 1 {
+			return 0, true
+		}
+		period := 2 * (n - 1)
+		i %= period
+		if i < 0 {
+			i += period
+		}
+		if i >= n {
+			i = period - i
+		}
+		return i, true
+	default:
+		if i < 0 {
+			return 0, true
+		}
+		return n - 1, true
+	}
+}
+
+// convolvePlane applies a kernel to a single channel stored row by row.
+func convolvePlane(src []float64, width, height int, kernel *Kernel, mode EdgeMode) []float64{
+	if kernel.IsSeparable() {
+		tmp := convolvePass(src, width, height, kernel.Row, 1, mode)
+		return convolvePass(tmp, width, height, kernel.Column, 0, mode)
+	}
+	dst := make([]float64, len(src))
+	ry, rx := kernel.Height/2, kernel.Width/2
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			sum := 0.0
+			for ky := -ry; ky <= ry; ky++ {
+				sy, ok := edgeIndex(y+ky, height, mode)
+				if !ok {
+					continue
+				}
+				for kx := -rx; kx <= rx; kx++ {
+					sx, ok := edgeIndex(x+kx, width, mode)
+					if !ok {
+						continue
+					}
+					sum += src[sy*width+sx] * kernel.Data[ky+ry][kx+rx]
+				}
+			}
+			dst[y*width+x] = sum
+		}
+	}
+	return dst
+}
+
+// convolvePass applies a 1D kernel horizontally (dx = 1) or vertically (dx = 0).
+func convolvePass(src []float64, width, height int, weights []float64, dx int, mode EdgeMode) []float64{
+	dst := make([]float64, len(src))
+	r := len(weights) / 2
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			sum := 0.0
+			for k := -r; k <= r; k++ {
+				var idx int
+				if dx == 1 {
+					sx, ok := edgeIndex(x+k, width, mode)
+					if !ok {
+						continue
+					}
+					idx = y*width + sx
+				} else {
+					sy, ok := edgeIndex(y+k, height, mode)
+					if !ok {
+						continue
+					}
+					idx = sy*width + x
+				}
+				sum += src[idx] * weights[k+r]
+			}
+			dst[y*width+x] = sum
+		}
+	}
+	return dst
+}
+
+// clampToMax rounds v and clamps it to [0, max].
+func clampToMax(v float64, max int) uint8{
+	if max > 255 || max <= 0 {
+		max = 255
+	}
+	v = math.Round(v)
+	if v < 0 {
+		return 0
+	}
+	if v > float64(max) {
+		return uint8(max)
+	}
+	return uint8(v)
+}
+
+// plane returns the PGM data as a flat float64 slice.
+func (pgm *PGM) plane() []float64{
+	plane := make([]float64, pgm.Width*pgm.Height)
+	for i := 0; i < pgm.Height; i++ {
+		for j := 0; j < pgm.Width; j++ {
+			plane[i*pgm.Width+j] = float64(pgm.Data[i][j])
+		}
+	}
+	return plane
+}
+
+// setPlane writes a flat float64 slice back to the PGM data, clamped to Max.
+func (pgm *PGM) setPlane(plane []float64){
+	for i := 0; i < pgm.Height; i++ {
+		for j := 0; j < pgm.Width; j++ {
+			pgm.Data[i][j] = clampToMax(plane[i*pgm.Width+j], pgm.Max)
+		}
+	}
+}
+
+// planeToPGM creates a new PGM from a flat float64 slice, clamped to max.
+func planeToPGM(plane []float64, width, height, max int) *PGM{
+	pgm := newPGM(width, height, max)
+	pgm.setPlane(plane)
+	return pgm
+}
+
+// planes returns the R, G and B channels of the PPM data as flat float64 slices.
+func (ppm *PPM) planes() (r, g, b []float64){
+	r = make([]float64, ppm.Width*ppm.Height)
+	g = make([]float64, ppm.Width*ppm.Height)
+	b = make([]float64, ppm.Width*ppm.Height)
+	for i := 0; i < ppm.Height; i++ {
+		for j := 0; j < ppm.Width; j++ {
+			p := ppm.Data[i][j]
+			r[i*ppm.Width+j] = float64(p.R)
+			g[i*ppm.Width+j] = float64(p.G)
+			b[i*ppm.Width+j] = float64(p.B)
+		}
+	}
+	return r, g, b
+}
+
+// setPlanes writes three flat float64 slices back to the PPM data, clamped to Max.
+func (ppm *PPM) setPlanes(r, g, b []float64){
+	for i := 0; i < ppm.Height; i++ {
+		for j := 0; j < ppm.Width; j++ {
+			k := i*ppm.Width + j
+			ppm.Data[i][j] = Pixel{clampToMax(r[k], ppm.Max), clampToMax(g[k], ppm.Max), clampToMax(b[k], ppm.Max)}
+		}
+	}
+}
+
+// Convolve applies the kernel to the PGM image, clamping the result to [0, Max].
+// The kernel is applied as a correlation (it is not flipped), as most image libraries do.
+func (pgm *PGM) Convolve(kernel *Kernel, mode EdgeMode){
+	pgm.setPlane(convolvePlane(pgm.plane(), pgm.Width, pgm.Height, kernel, mode))
+}
+
+// Convolve applies the kernel to each channel of the PPM image, clamping the result to [0, Max].
+// The kernel is applied as a correlation (it is not flipped), as most image libraries do.
+func (ppm *PPM) Convolve(kernel *Kernel, mode EdgeMode){
+	r, g, b := ppm.planes()
+	r = convolvePlane(r, ppm.Width, ppm.Height, kernel, mode)
+	g = convolvePlane(g, ppm.Width, ppm.Height, kernel, mode)
+	b = convolvePlane(b, ppm.Width, ppm.Height, kernel, mode)
+	ppm.setPlanes(r, g, b)
+}
+
+// GaussianBlur blurs the PGM image with a Gaussian of the given standard deviation.
+func (pgm *PGM) GaussianBlur(sigma float64){
+	pgm.Convolve(GaussianKernel(sigma), EdgeClamp)
+}
+
+// GaussianBlur blurs the PPM image with a Gaussian of the given standard deviation.
+func (ppm *PPM) GaussianBlur(sigma float64){
+	ppm.Convolve(GaussianKernel(sigma), EdgeClamp)
+}
+
+// BoxBlur replaces each pixel of the PGM image with the mean of its (2*radius+1)² neighbourhood.
+func (pgm *PGM) BoxBlur(radius int){
+	pgm.Convolve(BoxKernel(radius), EdgeClamp)
+}
+
+// BoxBlur replaces each pixel of the PPM image with the mean of its (2*radius+1)² neighbourhood.
+func (ppm *PPM) BoxBlur(radius int){
+	ppm.Convolve(BoxKernel(radius), EdgeClamp)
+}
+
+// Sharpen sharpens the PGM image with a 3x3 kernel.
+func (pgm *PGM) Sharpen(){
+	pgm.Convolve(SharpenKernel(), EdgeClamp)
+}
+
+// Sharpen sharpens the PPM image with a 3x3 kernel.
+func (ppm *PPM) Sharpen(){
+	ppm.Convolve(SharpenKernel(), EdgeClamp)
+}
+
+// UnsharpMask sharpens the PGM image by adding amount times the difference
+// between the image and its Gaussian blur. Differences smaller than threshold are ignored.
+func (pgm *PGM) UnsharpMask(sigma, amount float64, threshold int){
+	src := pgm.plane()
+	pgm.setPlane(unsharpPlane(src, pgm.Width, pgm.Height, sigma, amount, threshold))
+}
+
+// UnsharpMask sharpens the PPM image by adding amount times the difference
+// between each channel and its Gaussian blur. Differences smaller than threshold are ignored.
+func (ppm *PPM) UnsharpMask(sigma, amount float64, threshold int){
+	r, g, b := ppm.planes()
+	r = unsharpPlane(r, ppm.Width, ppm.Height, sigma, amount, threshold)
+	g = unsharpPlane(g, ppm.Width, ppm.Height, sigma, amount, threshold)
+	b = unsharpPlane(b, ppm.Width, ppm.Height, sigma, amount, threshold)
+	ppm.setPlanes(r, g, b)
+}
+
+// unsharpPlane applies an unsharp mask to a single channel.
+func unsharpPlane(src []float64, width, height int, sigma, amount float64, threshold int) []float64{
+	blurred := convolvePlane(src, width, height, GaussianKernel(sigma), EdgeClamp)
+	dst := make([]float64, len(src))
+	for i := range src {
+		diff := src[i] - blurred[i]
+		if math.Abs(diff) < float64(threshold) {
+			dst[i] = src[i]
+		} else {
+			dst[i] = src[i] + amount*diff
+		}
+	}
+	return dst
+}
\ No newline at end of file
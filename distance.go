@@ -0,0 +1,215 @@
+package netpbm
+
+import "math"
+
+// DistanceMetric selects how distances are measured by PBM.DistanceTransform.
+type DistanceMetric int
+
+const (
+	// DistanceEuclidean is the exact Euclidean distance.
+	DistanceEuclidean DistanceMetric = iota
+	// DistanceChamfer approximates the Euclidean distance with the 3-4 chamfer mask.
+	DistanceChamfer
+	// DistanceManhattan is the sum of the horizontal and vertical distances.
+	DistanceManhattan
+)
+
+// DistanceMap holds, for each pixel, the distance to the nearest feature pixel and the
+// position of that pixel. Pixels with no feature in the image have an infinite
+// distance and a nearest position of (-1, -1).
+type DistanceMap struct {
+	Data          [][]float64
+	Nearest       [][]Point
+	Width, Height int
+}
+
+// newDistanceMap returns a distance map of the given size with every distance infinite.
+func newDistanceMap(width, height int) *DistanceMap{
+	dm := &DistanceMap{Data: make([][]float64, height), Nearest: make([][]Point, height), Width: width, Height: height}
+	for i := 0; i < height; i++ {
+		dm.Data[i] = make([]float64, width)
+		dm.Nearest[i] = make([]Point, width)
+		for j := 0; j < width; j++ {
+			dm.Data[i][j] = math.Inf(1)
+			dm.Nearest[i][j] = Point{-1, -1}
+		}
+	}
+	return dm
+}
+
+// DistanceTransform returns the distance from each pixel of the PBM image to the
+// nearest set pixel, and the position of that pixel (the feature transform). Set
+// pixels have a distance of 0. To measure distances inside shapes, invert the image first.
+func (pbm *PBM) DistanceTransform(metric DistanceMetric) *DistanceMap{
+	if metric == DistanceEuclidean {
+		return pbm.euclideanDistance()
+	}
+	return pbm.chamferDistance(metric)
+}
+
+// distanceInfinity stands for the distance of pixels without a feature in the
+// Felzenszwalb-Huttenlocher transform; it is finite so that parabola intersections stay defined.
+const distanceInfinity = 1e20
+
+// squaredDistance1D computes the 1D squared Euclidean distance transform of f with the
+// lower envelope of parabolas of Felzenszwalb and Huttenlocher, writing the distances
+// to d and the index of the nearest sample to arg. v and z are scratch buffers of
+// length len(f) and len(f)+1.
+func squaredDistance1D(f, d []float64, arg, v []int, z []float64){
+	n := len(f)
+	k := 0
+	v[0] = 0
+	z[0], z[1] = math.Inf(-1), math.Inf(1)
+	for q := 1; q < n; q++ {
+		s := ((f[q] + float64(q*q)) - (f[v[k]] + float64(v[k]*v[k]))) / float64(2*q-2*v[k])
+		for s <= z[k] {
+			k--
+			s = ((f[q] + float64(q*q)) - (f[v[k]] + float64(v[k]*v[k]))) / float64(2*q-2*v[k])
+		}
+		k++
+		v[k] = q
+		z[k], z[k+1] = s, math.Inf(1)
+	}
+	k = 0
+	for q := 0; q < n; q++ {
+		for z[k+1] < float64(q) {
+			k++
+		}
+		d[q] = float64((q-v[k])*(q-v[k])) + f[v[k]]
+		arg[q] = v[k]
+	}
+}
+
+// euclideanDistance computes the exact Euclidean distance transform in two separable passes.
+func (pbm *PBM) euclideanDistance() *DistanceMap{
+	w, h := pbm.Width, pbm.Height
+	dm := newDistanceMap(w, h)
+	if w == 0 || h == 0 {
+		return dm
+	}
+	n := max(w, h)
+	f, d := make([]float64, n), make([]float64, n)
+	arg, v := make([]int, n), make([]int, n)
+	z := make([]float64, n+1)
+
+	// Columns: squared vertical distance to the nearest feature and its row
+	columnDistance := make([][]float64, h)
+	columnRow := make([][]int, h)
+	for y := range columnDistance {
+		columnDistance[y] = make([]float64, w)
+		columnRow[y] = make([]int, w)
+	}
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			if pbm.Data[y][x] {
+				f[y] = 0
+			} else {
+				f[y] = distanceInfinity
+			}
+		}
+		squaredDistance1D(f[:h], d[:h], arg[:h], v[:h], z[:h+1])
+		for y := 0; y < h; y++ {
+			columnDistance[y][x], columnRow[y][x] = d[y], arg[y]
+		}
+	}
+
+	// Rows: combine the column distances
+	for y := 0; y < h; y++ {
+		copy(f[:w], columnDistance[y])
+		squaredDistance1D(f[:w], d[:w], arg[:w], v[:w], z[:w+1])
+		for x := 0; x < w; x++ {
+			if d[x] >= distanceInfinity {
+				continue
+			}
+			dm.Data[y][x] = math.Sqrt(d[x])
+			dm.Nearest[y][x] = Point{arg[x], columnRow[y][arg[x]]}
+		}
+	}
+	return dm
+}
+
+// chamferDistance computes a chamfer or Manhattan distance transform with a forward
+// and a backward raster scan, propagating the nearest feature with the distances.
+func (pbm *PBM) chamferDistance(metric DistanceMetric) *DistanceMap{
+	w, h := pbm.Width, pbm.Height
+	dm := newDistanceMap(w, h)
+	// Offsets of the already visited neighbours in the forward scan, and their weights
+	type step struct {
+		dx, dy, weight int
+	}
+	forward, unit := []step{{-1, 0, 1}, {0, -1, 1}}, 1
+	if metric == DistanceChamfer {
+		forward, unit = []step{{-1, 0, 3}, {-1, -1, 4}, {0, -1, 3}, {1, -1, 4}}, 3
+	}
+	const infinity = math.MaxInt32
+	dist := make([][]int, h)
+	for y := 0; y < h; y++ {
+		dist[y] = make([]int, w)
+		for x := 0; x < w; x++ {
+			if pbm.Data[y][x] {
+				dm.Nearest[y][x] = Point{x, y}
+			} else {
+				dist[y][x] = infinity
+			}
+		}
+	}
+	relax := func(x, y, dx, dy, weight int){
+		nx, ny := x+dx, y+dy
+		if nx < 0 || ny < 0 || nx >= w || ny >= h || dist[ny][nx] == infinity {
+			return
+		}
+		if d := dist[ny][nx] + weight; d < dist[y][x] {
+			dist[y][x] = d
+			dm.Nearest[y][x] = dm.Nearest[ny][nx]
+		}
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			for _, s := range forward {
+				relax(x, y, s.dx, s.dy, s.weight)
+			}
+		}
+	}
+	for y := h - 1; y >= 0; y-- {
+		for x := w - 1; x >= 0; x-- {
+			for _, s := range forward {
+				relax(x, y, -s.dx, -s.dy, s.weight)
+			}
+		}
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if dist[y][x] != infinity {
+				dm.Data[y][x] = float64(dist[y][x]) / float64(unit)
+			}
+		}
+	}
+	return dm
+}
+
+// ToPGM returns a PGM image of the distances multiplied by scale, rounded and clamped
+// to max. Pixels without a feature are set to max.
+func (dm *DistanceMap) ToPGM(scale float64, max int) *PGM{
+	pgm := newPGM(dm.Width, dm.Height, max)
+	for y := 0; y < dm.Height; y++ {
+		for x := 0; x < dm.Width; x++ {
+			pgm.Data[y][x] = clampToMax(dm.Data[y][x]*scale, max)
+		}
+	}
+	return pgm
+}
+
+// Voronoi returns a label image where each pixel has the label of its nearest feature
+// in labels, such as the components of the PBM image that was transformed. This
+// partitions the image into the Voronoi regions of the labelled shapes.
+func (dm *DistanceMap) Voronoi(labels *LabelImage) *LabelImage{
+	regions := newLabelImage(dm.Width, dm.Height)
+	for y := 0; y < dm.Height; y++ {
+		for x := 0; x < dm.Width; x++ {
+			if p := dm.Nearest[y][x]; p.X >= 0 {
+				regions.Data[y][x] = labels.Data[p.Y][p.X]
+			}
+		}
+	}
+	return regions
+}
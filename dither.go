@@ -0,0 +1,337 @@
+package netpbm
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+)
+
+// DitherMethod selects the dithering algorithm used when reducing the number of levels.
+type DitherMethod int
+
+const (
+	// DitherNone quantizes each pixel to the nearest level.
+	DitherNone DitherMethod = iota
+	// FloydSteinberg diffuses the error to 4 neighbours.
+	FloydSteinberg
+	// Atkinson diffuses 3/4 of the error to 6 neighbours.
+	Atkinson
+	// JarvisJudiceNinke diffuses the error to 12 neighbours.
+	JarvisJudiceNinke
+	// Stucki diffuses the error to 12 neighbours with weights favouring the closest.
+	Stucki
+	// Sierra diffuses the error to 10 neighbours.
+	Sierra
+	// Bayer2 uses a 2x2 ordered dither matrix.
+	Bayer2
+	// Bayer4 uses a 4x4 ordered dither matrix.
+	Bayer4
+	// Bayer8 uses an 8x8 ordered dither matrix.
+	Bayer8
+	// BlueNoise uses a 64x64 void-and-cluster blue noise mask.
+	BlueNoise
+)
+
+// DitherOptions configures dithering.
+// Serpentine alternates the scan direction on each row for error diffusion methods.
+type DitherOptions struct {
+	Method     DitherMethod
+	Serpentine bool
+}
+
+// diffusion is one entry of an error diffusion matrix.
+type diffusion struct {
+	dx, dy int
+	weight float64
+}
+
+// diffusionMatrix returns the error diffusion matrix of the method, or nil for ordered methods.
+func diffusionMatrix(method DitherMethod) []diffusion{
+	switch method {
+	case FloydSteinberg:
+		return []diffusion{{1, 0, 7.0 / 16}, {-1, 1, 3.0 / 16}, {0, 1, 5.0 / 16}, {1, 1, 1.0 / 16}}
+	case Atkinson:
+		return []diffusion{{1, 0, 1.0 / 8}, {2, 0, 1.0 / 8}, {-1, 1, 1.0 / 8}, {0, 1, 1.0 / 8}, {1, 1, 1.0 / 8}, {0, 2, 1.0 / 8}}
+	case JarvisJudiceNinke:
+		return []diffusion{
+			{1, 0, 7.0 / 48}, {2, 0, 5.0 / 48},
+			{-2, 1, 3.0 / 48}, {-1, 1, 5.0 / 48}, {0, 1, 7.0 / 48}, {1, 1, 5.0 / 48}, {2, 1, 3.0 / 48},
+			{-2, 2, 1.0 / 48}, {-1, 2, 3.0 / 48}, {0, 2, 5.0 / 48}, {1, 2, 3.0 / 48}, {2, 2, 1.0 / 48},
+		}
+	case Stucki:
+		return []diffusion{
+			{1, 0, 8.0 / 42}, {2, 0, 4.0 / 42},
+			{-2, 1, 2.0 / 42}, {-1, 1, 4.0 / 42}, {0, 1, 8.0 / 42}, {1, 1, 4.0 / 42}, {2, 1, 2.0 / 42},
+			{-2, 2, 1.0 / 42}, {-1, 2, 2.0 / 42}, {0, 2, 4.0 / 42}, {1, 2, 2.0 / 42}, {2, 2, 1.0 / 42},
+		}
+	case Sierra:
+		return []diffusion{
+			{1, 0, 5.0 / 32}, {2, 0, 3.0 / 32},
+			{-2, 1, 2.0 / 32}, {-1, 1, 4.0 / 32}, {0, 1, 5.0 / 32}, {1, 1, 4.0 / 32}, {2, 1, 2.0 / 32},
+			{-1, 2, 2.0 / 32}, {0, 2, 3.0 / 32}, {1, 2, 2.0 / 32},
+		}
+	}
+	return nil
+}
+
+// ditherThreshold returns the ordered dither value in [0, 1) at (x, y),
+// or false if the method is not an ordered one.
+func ditherThreshold(method DitherMethod, x, y int) (float64, bool){
+	switch method {
+	case Bayer2:
+		return bayerValue(1, x, y), true
+	case Bayer4:
+		return bayerValue(2, x, y), true
+	case Bayer8:
+		return bayerValue(3, x, y), true
+	case BlueNoise:
+		mask := blueNoiseMask()
+		return mask[(y%blueNoiseSize)*blueNoiseSize+x%blueNoiseSize], true
+	}
+	return 0, false
+}
+
+// bayerValue returns the normalized value of the 2^order Bayer matrix at (x, y).
+func bayerValue(order, x, y int) float64{
+	size := 1 << order
+	x, y = x%size, y%size
+	v := 0
+	for bit := 0; bit < order; bit++ {
+		xb, yb := (x>>bit)&1, (y>>bit)&1
+		v |= ((xb ^ yb) << (2*(order-bit) - 1)) | (yb << (2*(order-bit) - 2))
+	}
+	return (float64(v) + 0.5) / float64(size*size)
+}
+
+const blueNoiseSize = 64
+
+var (
+	blueNoiseOnce sync.Once
+	blueNoise     []float64
+)
+
+// blueNoiseMask returns a blueNoiseSize² mask of values in [0, 1), generated once
+// with the void-and-cluster algorithm.
+func blueNoiseMask() []float64{
+	blueNoiseOnce.Do(func(){
+		const n = blueNoiseSize * blueNoiseSize
+		const sigma = 1.5
+
+		// Toroidal Gaussian energy contributed by a single set pixel
+		lut := make([]float64, n)
+		for dy := 0; dy < blueNoiseSize; dy++ {
+			for dx := 0; dx < blueNoiseSize; dx++ {
+				ddx := float64(min(dx, blueNoiseSize-dx))
+				ddy := float64(min(dy, blueNoiseSize-dy))
+				lut[dy*blueNoiseSize+dx] = math.Exp(-(ddx*ddx + ddy*ddy) / (2 * sigma * sigma))
+			}
+		}
+		pattern := make([]bool, n)
+		energy := make([]float64, n)
+		toggle := func(p int, set bool){
+			pattern[p] = set
+			px, py := p%blueNoiseSize, p/blueNoiseSize
+			sign := 1.0
+			if !set {
+				sign = -1
+			}
+			for q := 0; q < n; q++ {
+				dx := (q%blueNoiseSize - px + blueNoiseSize) % blueNoiseSize
+				dy := (q/blueNoiseSize - py + blueNoiseSize) % blueNoiseSize
+				energy[q] += sign * lut[dy*blueNoiseSize+dx]
+			}
+		}
+		tightestCluster := func() int{
+			best := -1
+			for p := 0; p < n; p++ {
+				if pattern[p] && (best < 0 || energy[p] > energy[best]) {
+					best = p
+				}
+			}
+			return best
+		}
+		largestVoid := func() int{
+			best := -1
+			for p := 0; p < n; p++ {
+				if !pattern[p] && (best < 0 || energy[p] < energy[best]) {
+					best = p
+				}
+			}
+			return best
+		}
+
+		// Initial pattern: random points, then spread them evenly
+		random := rand.New(rand.NewSource(1))
+		ones := n / 10
+		for _, p := range random.Perm(n)[:ones] {
+			toggle(p, true)
+		}
+		for {
+			cluster := tightestCluster()
+			toggle(cluster, false)
+			void := largestVoid()
+			toggle(void, true)
+			if void == cluster {
+				break
+			}
+		}
+		prototype := append([]bool(nil), pattern...)
+		prototypeEnergy := append([]float64(nil), energy...)
+
+		ranks := make([]int, n)
+		for rank := ones - 1; rank >= 0; rank-- {
+			cluster := tightestCluster()
+			toggle(cluster, false)
+			ranks[cluster] = rank
+		}
+		copy(pattern, prototype)
+		copy(energy, prototypeEnergy)
+		for rank := ones; rank < n; rank++ {
+			void := largestVoid()
+			toggle(void, true)
+			ranks[void] = rank
+		}
+
+		blueNoise = make([]float64, n)
+		for p, rank := range ranks {
+			blueNoise[p] = (float64(rank) + 0.5) / n
+		}
+	})
+	return blueNoise
+}
+
+// ditherBuffer quantizes an interleaved buffer of the given number of channels in place.
+// quantize replaces a pixel with its nearest available value. spread is the distance
+// between two levels, used to scale the ordered dither offsets.
+func ditherBuffer(buf []float64, width, height, channels int, options DitherOptions, spread float64, quantize func(pixel []float64)){
+	if matrix := diffusionMatrix(options.Method); matrix != nil {
+		old := make([]float64, channels)
+		for y := 0; y < height; y++ {
+			reverse := options.Serpentine && y%2 == 1
+			for i := 0; i < width; i++ {
+				x := i
+				if reverse {
+					x = width - 1 - i
+				}
+				pixel := buf[(y*width+x)*channels : (y*width+x+1)*channels]
+				copy(old, pixel)
+				quantize(pixel)
+				for _, d := range matrix {
+					dx := d.dx
+					if reverse {
+						dx = -dx
+					}
+					nx, ny := x+dx, y+d.dy
+					if nx < 0 || nx >= width || ny >= height {
+						continue
+					}
+					k := (ny*width + nx) * channels
+					for c := 0; c < channels; c++ {
+						buf[k+c] += (old[c] - pixel[c]) * d.weight
+					}
+				}
+			}
+		}
+		return
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			pixel := buf[(y*width+x)*channels : (y*width+x+1)*channels]
+			if t, ok := ditherThreshold(options.Method, x, y); ok {
+				for c := range pixel {
+					pixel[c] += (t - 0.5) * spread
+				}
+			}
+			quantize(pixel)
+		}
+	}
+}
+
+// ditherToPBM converts the PGM image to PBM by dithering between 0 and Max.
+func (pgm *PGM) ditherToPBM(options DitherOptions) *PBM{
+	buf := pgm.plane()
+	maxValue := float64(pgm.Max)
+	ditherBuffer(buf, pgm.Width, pgm.Height, 1, options, maxValue, func(pixel []float64){
+		if pixel[0] > maxValue/2 {
+			pixel[0] = maxValue
+		} else {
+			pixel[0] = 0
+		}
+	})
+	pbm := newPBM(pgm.Width, pgm.Height)
+	for i := 0; i < pgm.Height; i++ {
+		for j := 0; j < pgm.Width; j++ {
+			pbm.Data[i][j] = buf[i*pgm.Width+j] > 0
+		}
+	}
+	return pbm
+}
+
+// interleaved returns the PPM data as a flat R, G, B float64 slice.
+func (ppm *PPM) interleaved() []float64{
+	buf := make([]float64, 0, ppm.Width*ppm.Height*3)
+	for i := 0; i < ppm.Height; i++ {
+		for j := 0; j < ppm.Width; j++ {
+			p := ppm.Data[i][j]
+			buf = append(buf, float64(p.R), float64(p.G), float64(p.B))
+		}
+	}
+	return buf
+}
+
+// DitherToPalette replaces each pixel of the PPM image with a color of the palette,
+// dithering the difference.
+func (ppm *PPM) DitherToPalette(palette []Pixel, options DitherOptions){
+	if len(palette) == 0 {
+		return
+	}
+	buf := ppm.interleaved()
+	spread := float64(ppm.Max) / math.Cbrt(float64(len(palette)))
+	ditherBuffer(buf, ppm.Width, ppm.Height, 3, options, spread, func(pixel []float64){
+		best := nearestColor(palette, pixel[0], pixel[1], pixel[2])
+		pixel[0], pixel[1], pixel[2] = float64(palette[best].R), float64(palette[best].G), float64(palette[best].B)
+	})
+	for i := 0; i < ppm.Height; i++ {
+		for j := 0; j < ppm.Width; j++ {
+			k := (i*ppm.Width + j) * 3
+			ppm.Data[i][j] = palette[nearestColor(palette, buf[k], buf[k+1], buf[k+2])]
+		}
+	}
+}
+
+// nearestColor returns the index of the palette color closest to (r, g, b).
+func nearestColor(palette []Pixel, r, g, b float64) int{
+	best, bestDistance := 0, math.Inf(1)
+	for i, p := range palette {
+		dr, dg, db := float64(p.R)-r, float64(p.G)-g, float64(p.B)-b
+		if distance := dr*dr + dg*dg + db*db; distance < bestDistance {
+			best, bestDistance = i, distance
+		}
+	}
+	return best
+}
+
+// ReduceMax rescales the PPM image to the new max value, dithering the rounding error.
+func (ppm *PPM) ReduceMax(newMax int, options DitherOptions){
+	if newMax <= 0 || ppm.Max <= 0 {
+		return
+	}
+	buf := ppm.interleaved()
+	step := float64(ppm.Max) / float64(newMax)
+	ditherBuffer(buf, ppm.Width, ppm.Height, 3, options, step, func(pixel []float64){
+		for c := range pixel {
+			pixel[c] = math.Max(0, math.Min(float64(newMax), math.Round(pixel[c]/step))) * step
+		}
+	})
+	for i := 0; i < ppm.Height; i++ {
+		for j := 0; j < ppm.Width; j++ {
+			k := (i*ppm.Width + j) * 3
+			ppm.Data[i][j] = Pixel{
+				clampToMax(buf[k]/step, newMax),
+				clampToMax(buf[k+1]/step, newMax),
+				clampToMax(buf[k+2]/step, newMax),
+			}
+		}
+	}
+	ppm.Max = newMax
+}
\ No newline at end of file
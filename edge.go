@@ -0,0 +1,175 @@
+package netpbm
+
+import "math"
+
+// EdgeOperator selects the derivative kernels used to compute image gradients.
+type EdgeOperator int
+
+const (
+	// Sobel uses the [1 2 1] smoothing / [-1 0 1] derivative kernels.
+	Sobel EdgeOperator = iota
+	// Prewitt uses the [1 1 1] smoothing / [-1 0 1] derivative kernels.
+	Prewitt
+	// Scharr uses the [3 10 3] smoothing / [-1 0 1] derivative kernels.
+	Scharr
+)
+
+// gradientKernels returns the horizontal and vertical derivative kernels of the operator.
+func gradientKernels(op EdgeOperator) (kx, ky *Kernel){
+	derivative := []float64{-1, 0, 1}
+	var smooth []float64
+	switch op {
+	case Prewitt:
+		smooth = []float64{1, 1, 1}
+	case Scharr:
+		smooth = []float64{3, 10, 3}
+	default:
+		smooth = []float64{1, 2, 1}
+	}
+	kx, _ = NewSeparableKernel(derivative, smooth)
+	ky, _ = NewSeparableKernel(smooth, derivative)
+	return kx, ky
+}
+
+// gradientPlanes returns the horizontal and vertical derivatives of a channel.
+func gradientPlanes(src []float64, width, height int, op EdgeOperator) (gx, gy []float64){
+	kx, ky := gradientKernels(op)
+	gx = convolvePlane(src, width, height, kx, EdgeClamp)
+	gy = convolvePlane(src, width, height, ky, EdgeClamp)
+	return gx, gy
+}
+
+// GradientMagnitude returns a PGM of the gradient magnitude of the image, clamped to Max.
+func (pgm *PGM) GradientMagnitude(op EdgeOperator) *PGM{
+	gx, gy := gradientPlanes(pgm.plane(), pgm.Width, pgm.Height, op)
+	magnitude := make([]float64, len(gx))
+	for i := range gx {
+		magnitude[i] = math.Hypot(gx[i], gy[i])
+	}
+	return planeToPGM(magnitude, pgm.Width, pgm.Height, pgm.Max)
+}
+
+// GradientDirection returns a PGM of the gradient direction of the image.
+// Angles from -π to π are mapped linearly to 0..Max.
+func (pgm *PGM) GradientDirection(op EdgeOperator) *PGM{
+	gx, gy := gradientPlanes(pgm.plane(), pgm.Width, pgm.Height, op)
+	direction := make([]float64, len(gx))
+	for i := range gx {
+		direction[i] = (math.Atan2(gy[i], gx[i]) + math.Pi) / (2 * math.Pi) * float64(pgm.Max)
+	}
+	return planeToPGM(direction, pgm.Width, pgm.Height, pgm.Max)
+}
+
+// Laplacian returns a PGM of the absolute Laplacian of the image, clamped to Max.
+func (pgm *PGM) Laplacian() *PGM{
+	kernel, _ := NewKernel([][]float64{
+		{0, 1, 0},
+		{1, -4, 1},
+		{0, 1, 0},
+	})
+	laplacian := convolvePlane(pgm.plane(), pgm.Width, pgm.Height, kernel, EdgeClamp)
+	for i := range laplacian {
+		laplacian[i] = math.Abs(laplacian[i])
+	}
+	return planeToPGM(laplacian, pgm.Width, pgm.Height, pgm.Max)
+}
+
+// Canny returns a PBM where edge pixels are set, using the Canny detector.
+// The image is first smoothed with a Gaussian of standard deviation sigma,
+// then Sobel gradients are thinned by non-maximum suppression. Pixels whose
+// magnitude is above high are edges, and pixels above low are kept when they
+// are connected to an edge.
+func (pgm *PGM) Canny(sigma, low, high float64) *PBM{
+	width, height := pgm.Width, pgm.Height
+	src := convolvePlane(pgm.plane(), width, height, GaussianKernel(sigma), EdgeClamp)
+	gx, gy := gradientPlanes(src, width, height, Sobel)
+	magnitude := make([]float64, len(gx))
+	for i := range gx {
+		magnitude[i] = math.Hypot(gx[i], gy[i])
+	}
+
+	// Non-maximum suppression
+	thin := make([]float64, len(magnitude))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			i := y*width + x
+			m := magnitude[i]
+			if m == 0 {
+				continue
+			}
+			angle := math.Atan2(gy[i], gx[i]) * 180 / math.Pi
+			if angle < 0 {
+				angle += 180
+			}
+			var dx, dy int
+			switch {
+			case angle < 22.5 || angle >= 157.5:
+				dx, dy = 1, 0
+			case angle < 67.5:
+				dx, dy = 1, 1
+			case angle < 112.5:
+				dx, dy = 0, 1
+			default:
+				dx, dy = -1, 1
+			}
+			if m >= magnitudeAt(magnitude, width, height, x+dx, y+dy) && m >= magnitudeAt(magnitude, width, height, x-dx, y-dy) {
+				thin[i] = m
+			}
+		}
+	}
+
+	// Hysteresis
+	pbm := newPBM(width, height)
+	var stack []int
+	for i, m := range thin {
+		if m >= high && !pbm.Data[i/width][i%width] {
+			pbm.Data[i/width][i%width] = true
+			stack = append(stack, i)
+			for len(stack) > 0 {
+				j := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				x, y := j%width, j/width
+				for ny := y - 1; ny <= y+1; ny++ {
+					for nx := x - 1; nx <= x+1; nx++ {
+						if nx < 0 || ny < 0 || nx >= width || ny >= height || pbm.Data[ny][nx] {
+							continue
+						}
+						if thin[ny*width+nx] >= low {
+							pbm.Data[ny][nx] = true
+							stack = append(stack, ny*width+nx)
+						}
+					}
+				}
+			}
+		}
+	}
+	return pbm
+}
+
+// magnitudeAt returns the value at (x, y), or 0 outside the image.
+func magnitudeAt(plane []float64, width, height, x, y int) float64{
+	if x < 0 || y < 0 || x >= width || y >= height {
+		return 0
+	}
+	return plane[y*width+x]
+}
+
+// GradientMagnitude returns a PGM of the gradient magnitude of the grayscale image.
+func (ppm *PPM) GradientMagnitude(op EdgeOperator) *PGM{
+	return ppm.ToPGM().GradientMagnitude(op)
+}
+
+// GradientDirection returns a PGM of the gradient direction of the grayscale image.
+func (ppm *PPM) GradientDirection(op EdgeOperator) *PGM{
+	return ppm.ToPGM().GradientDirection(op)
+}
+
+// Laplacian returns a PGM of the absolute Laplacian of the grayscale image.
+func (ppm *PPM) Laplacian() *PGM{
+	return ppm.ToPGM().Laplacian()
+}
+
+// Canny returns a PBM of the edges of the grayscale image, see PGM.Canny.
+func (ppm *PPM) Canny(sigma, low, high float64) *PBM{
+	return ppm.ToPGM().Canny(sigma, low, high)
+}
\ No newline at end of file
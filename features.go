@@ -0,0 +1,322 @@
+package netpbm
+
+import (
+	"math"
+	"math/bits"
+	"math/rand"
+	"sort"
+)
+
+// Keypoint is a feature point of an image. Response is the detector score, and Angle
+// the orientation in radians computed by PGM.Describe.
+type Keypoint struct {
+	Position Point
+	Response float64
+	Angle    float64
+}
+
+// Descriptor is a 256 bit binary descriptor of the patch around a keypoint.
+type Descriptor [4]uint64
+
+// Distance returns the Hamming distance between two descriptors.
+func (d Descriptor) Distance(other Descriptor) int{
+	distance := 0
+	for i := range d {
+		distance += bits.OnesCount64(d[i] ^ other[i])
+	}
+	return distance
+}
+
+// DescriptorMatch pairs descriptor Query of the first set with descriptor Train of the second.
+type DescriptorMatch struct {
+	Query, Train int
+	Distance     int
+}
+
+// cornerResponse returns the Harris response, or the smallest eigenvalue of the
+// structure tensor for Shi-Tomasi, at each pixel of the PGM image. Sobel gradients of
+// the image normalized to 0..1 are summed over a Gaussian window of standard deviation 1.5.
+func (pgm *PGM) cornerResponse(shiTomasi bool) []float64{
+	w, h := pgm.Width, pgm.Height
+	plane := pgm.plane()
+	for i := range plane {
+		plane[i] /= float64(max(pgm.Max, 1))
+	}
+	gx, gy := gradientPlanes(plane, w, h, Sobel)
+	xx, yy, xy := make([]float64, len(plane)), make([]float64, len(plane)), make([]float64, len(plane))
+	for i := range plane {
+		xx[i], yy[i], xy[i] = gx[i]*gx[i], gy[i]*gy[i], gx[i]*gy[i]
+	}
+	kernel := GaussianKernel(1.5)
+	sxx := convolvePlane(xx, w, h, kernel, EdgeMirror)
+	syy := convolvePlane(yy, w, h, kernel, EdgeMirror)
+	sxy := convolvePlane(xy, w, h, kernel, EdgeMirror)
+	response := make([]float64, len(plane))
+	for i := range response {
+		det := sxx[i]*syy[i] - sxy[i]*sxy[i]
+		trace := sxx[i] + syy[i]
+		if shiTomasi {
+			response[i] = trace/2 - math.Sqrt(math.Max(trace*trace/4-det, 0))
+		} else {
+			response[i] = det - 0.04*trace*trace
+		}
+	}
+	return response
+}
+
+// selectKeypoints returns the local maxima of response that are above quality times the
+// strongest response, strongest first, at least minDistance apart and at most
+// maxCorners of them (0 means no limit).
+func selectKeypoints(response []float64, width, height, maxCorners int, quality float64, minDistance int) []Keypoint{
+	strongest := 0.0
+	for _, r := range response {
+		strongest = math.Max(strongest, r)
+	}
+	if strongest <= 0 {
+		return nil
+	}
+	var candidates []Keypoint
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			r := response[y*width+x]
+			if r <= 0 || r < quality*strongest {
+				continue
+			}
+			peak := true
+			for dy := -1; dy <= 1 && peak; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := x+dx, y+dy
+					if (dx == 0 && dy == 0) || nx < 0 || ny < 0 || nx >= width || ny >= height {
+						continue
+					}
+					if n := response[ny*width+nx]; n > r || (n == r && ny*width+nx < y*width+x) {
+						peak = false
+						break
+					}
+				}
+			}
+			if peak {
+				candidates = append(candidates, Keypoint{Position: Point{x, y}, Response: r})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool{
+		return candidates[i].Response > candidates[j].Response
+	})
+	var keypoints []Keypoint
+	for _, c := range candidates {
+		if maxCorners > 0 && len(keypoints) == maxCorners {
+			break
+		}
+		keep := true
+		for _, k := range keypoints {
+			dx, dy := c.Position.X-k.Position.X, c.Position.Y-k.Position.Y
+			if dx*dx+dy*dy < minDistance*minDistance {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			keypoints = append(keypoints, c)
+		}
+	}
+	return keypoints
+}
+
+// HarrisCorners returns the corners of the PGM image found by the Harris detector
+// (k = 0.04), strongest first. Corners weaker than quality (0 to 1) times the strongest
+// one or closer than minDistance to a stronger one are dropped, and at most maxCorners
+// are returned (0 means no limit).
+func (pgm *PGM) HarrisCorners(maxCorners int, quality float64, minDistance int) []Keypoint{
+	return selectKeypoints(pgm.cornerResponse(false), pgm.Width, pgm.Height, maxCorners, quality, minDistance)
+}
+
+// ShiTomasiCorners returns the corners of the PGM image scored by the smallest
+// eigenvalue of the structure tensor. The parameters are those of HarrisCorners.
+func (pgm *PGM) ShiTomasiCorners(maxCorners int, quality float64, minDistance int) []Keypoint{
+	return selectKeypoints(pgm.cornerResponse(true), pgm.Width, pgm.Height, maxCorners, quality, minDistance)
+}
+
+// fastCircle is the Bresenham circle of radius 3 tested by the FAST detector.
+var fastCircle = [16]Point{
+	{0, -3}, {1, -3}, {2, -2}, {3, -1}, {3, 0}, {3, 1}, {2, 2}, {1, 3},
+	{0, 3}, {-1, 3}, {-2, 2}, {-3, 1}, {-3, 0}, {-3, -1}, {-2, -2}, {-1, -3},
+}
+
+// FASTCorners returns the corners of the PGM image found by the FAST-9 detector: pixels
+// with 9 contiguous pixels of the surrounding circle all brighter, or all darker, by
+// more than threshold. The response is the sum of the differences beyond threshold;
+// only local maxima are kept, strongest first, and at most maxCorners (0 means no limit).
+func (pgm *PGM) FASTCorners(threshold, maxCorners int) []Keypoint{
+	w, h := pgm.Width, pgm.Height
+	response := make([]float64, w*h)
+	var ring [16]int
+	for y := 3; y < h-3; y++ {
+		for x := 3; x < w-3; x++ {
+			center := int(pgm.Data[y][x])
+			for i, o := range fastCircle {
+				ring[i] = int(pgm.Data[y+o.Y][x+o.X]) - center
+			}
+			for _, sign := range []int{1, -1} {
+				run, best := 0, 0
+				for i := 0; i < 16+8; i++ {
+					if sign*ring[i%16] > threshold {
+						run++
+						best = max(best, run)
+					} else {
+						run = 0
+					}
+				}
+				if best < 9 {
+					continue
+				}
+				score := 0
+				for _, d := range ring {
+					if sign*d > threshold {
+						score += sign*d - threshold
+					}
+				}
+				response[y*w+x] = math.Max(response[y*w+x], float64(score))
+			}
+		}
+	}
+	return selectKeypoints(response, w, h, maxCorners, 0, 0)
+}
+
+// orbPatchRadius is the radius of the patch described around each keypoint.
+const orbPatchRadius = 15
+
+// orbPattern holds the pairs of points compared by the descriptor, as offsets within
+// the patch. They follow an isotropic Gaussian distribution, as in BRIEF, and are
+// generated with a fixed seed so that descriptors are reproducible.
+var orbPattern = func() [256][2]Point{
+	var pattern [256][2]Point
+	random := rand.New(rand.NewSource(31))
+	sample := func() Point{
+		for {
+			p := Point{int(math.Round(random.NormFloat64() * 31 / 5)), int(math.Round(random.NormFloat64() * 31 / 5))}
+			if p.X*p.X+p.Y*p.Y <= orbPatchRadius*orbPatchRadius {
+				return p
+			}
+		}
+	}
+	for i := range pattern {
+		pattern[i][0] = sample()
+		for pattern[i][1] = sample(); pattern[i][1] == pattern[i][0]; pattern[i][1] = sample() {
+		}
+	}
+	return pattern
+}()
+
+// Describe computes ORB-style descriptors of the keypoints of the PGM image. The
+// orientation of each keypoint is the direction of the intensity centroid of its
+// patch, and the BRIEF point pairs are rotated accordingly on the image smoothed by a
+// Gaussian of standard deviation 2. Keypoints too close to the border are dropped; the
+// kept keypoints, with their Angle set, are returned with their descriptors.
+func (pgm *PGM) Describe(keypoints []Keypoint) ([]Keypoint, []Descriptor){
+	w, h := pgm.Width, pgm.Height
+	plane := pgm.plane()
+	smooth := convolvePlane(plane, w, h, GaussianKernel(2), EdgeMirror)
+	margin := orbPatchRadius + 1
+	var kept []Keypoint
+	var descriptors []Descriptor
+	for _, k := range keypoints {
+		x, y := k.Position.X, k.Position.Y
+		if x < margin || y < margin || x >= w-margin || y >= h-margin {
+			continue
+		}
+		m10, m01 := 0.0, 0.0
+		for dy := -orbPatchRadius; dy <= orbPatchRadius; dy++ {
+			for dx := -orbPatchRadius; dx <= orbPatchRadius; dx++ {
+				if dx*dx+dy*dy <= orbPatchRadius*orbPatchRadius {
+					v := plane[(y+dy)*w+x+dx]
+					m10 += float64(dx) * v
+					m01 += float64(dy) * v
+				}
+			}
+		}
+		k.Angle = math.Atan2(m01, m10)
+		cos, sin := math.Cos(k.Angle), math.Sin(k.Angle)
+		at := func(p Point) float64{
+			rx := int(math.Round(float64(p.X)*cos - float64(p.Y)*sin))
+			ry := int(math.Round(float64(p.X)*sin + float64(p.Y)*cos))
+			return smooth[(y+ry)*w+x+rx]
+		}
+		var d Descriptor
+		for i, pair := range orbPattern {
+			if at(pair[0]) < at(pair[1]) {
+				d[i/64] |= 1 << (i % 64)
+			}
+		}
+		kept = append(kept, k)
+		descriptors = append(descriptors, d)
+	}
+	return kept, descriptors
+}
+
+// MatchDescriptors returns, for each descriptor of query, its nearest descriptor in
+// train by Hamming distance if it is at most maxDistance. With crossCheck, a match is
+// kept only if the query descriptor is also the nearest to its train descriptor.
+// Matches are sorted by distance.
+func MatchDescriptors(query, train []Descriptor, maxDistance int, crossCheck bool) []DescriptorMatch{
+	nearest := func(d Descriptor, set []Descriptor) (int, int){
+		best, bestDistance := -1, math.MaxInt
+		for i, other := range set {
+			if distance := d.Distance(other); distance < bestDistance {
+				best, bestDistance = i, distance
+			}
+		}
+		return best, bestDistance
+	}
+	var matches []DescriptorMatch
+	for i, d := range query {
+		j, distance := nearest(d, train)
+		if j < 0 || distance > maxDistance {
+			continue
+		}
+		if crossCheck {
+			if back, _ := nearest(train[j], query); back != i {
+				continue
+			}
+		}
+		matches = append(matches, DescriptorMatch{i, j, distance})
+	}
+	sort.SliceStable(matches, func(i, j int) bool{
+		return matches[i].Distance < matches[j].Distance
+	})
+	return matches
+}
+
+// DrawKeypoints draws a circle of radius 3 around each keypoint, made smaller near the
+// borders so that it stays inside the image.
+func (ppm *PPM) DrawKeypoints(keypoints []Keypoint, color Pixel){
+	for _, k := range keypoints {
+		p := k.Position
+		if p.X < 0 || p.Y < 0 || p.X >= ppm.Width || p.Y >= ppm.Height {
+			continue
+		}
+		ppm.DrawCircle(p, min(3, p.X, p.Y, ppm.Width-1-p.X, ppm.Height-1-p.Y), color)
+	}
+}
+
+// DrawMatches returns a PPM image with a and b side by side, with the max value of a,
+// where the keypoints of each match are circled and joined by a line. Each match has
+// its own color.
+func DrawMatches(a, b *PPM, keypointsA, keypointsB []Keypoint, matches []DescriptorMatch) *PPM{
+	out := newPPM(a.Width+b.Width, max(a.Height, b.Height), a.Max)
+	for y := 0; y < a.Height; y++ {
+		copy(out.Data[y], a.Data[y])
+	}
+	for y := 0; y < b.Height; y++ {
+		copy(out.Data[y][a.Width:], b.Data[y])
+	}
+	for i, m := range matches {
+		color := labelColor(i + 1)
+		p := keypointsA[m.Query].Position
+		q := keypointsB[m.Train].Position
+		q.X += a.Width
+		out.DrawKeypoints([]Keypoint{{Position: p}, {Position: q}}, color)
+		out.DrawLine(p, q, color)
+	}
+	return out
+}
\ No newline at end of file
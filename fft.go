@@ -0,0 +1,265 @@
+package netpbm
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// Spectrum is the 2D discrete Fourier transform of a PGM image padded to power of two
+// dimensions. Data is stored row by row with the zero frequency at index 0.
+type Spectrum struct {
+	Data                    []complex128
+	Width, Height           int
+	ImageWidth, ImageHeight int
+	Max                     int
+}
+
+// FrequencyFilter selects the transfer function of the spectrum filters.
+type FrequencyFilter int
+
+const (
+	// FilterIdeal has a sharp cutoff, which causes ringing.
+	FilterIdeal FrequencyFilter = iota
+	// FilterGaussian has a smooth Gaussian transition.
+	FilterGaussian
+	// FilterButterworth has a second order Butterworth transition.
+	FilterButterworth
+)
+
+// nextPowerOfTwo returns the smallest power of two greater than or equal to n.
+func nextPowerOfTwo(n int) int{
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
+
+// fft1D computes in place the discrete Fourier transform of data, whose length is a
+// power of two, or its unnormalized inverse.
+func fft1D(data []complex128, inverse bool){
+	n := len(data)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			data[i], data[j] = data[j], data[i]
+		}
+	}
+	sign := -1.0
+	if inverse {
+		sign = 1
+	}
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Rect(1, sign*2*math.Pi/float64(size))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				a, b := data[start+k], data[start+k+size/2]*w
+				data[start+k], data[start+k+size/2] = a+b, a-b
+				w *= step
+			}
+		}
+	}
+}
+
+// fft2D computes in place the 2D transform of a width x height array, or its normalized inverse.
+func fft2D(data []complex128, width, height int, inverse bool){
+	for y := 0; y < height; y++ {
+		fft1D(data[y*width:(y+1)*width], inverse)
+	}
+	column := make([]complex128, height)
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			column[y] = data[y*width+x]
+		}
+		fft1D(column, inverse)
+		for y := 0; y < height; y++ {
+			data[y*width+x] = column[y]
+		}
+	}
+	if inverse {
+		scale := complex(1/float64(width*height), 0)
+		for i := range data {
+			data[i] *= scale
+		}
+	}
+}
+
+// FFT returns the spectrum of the PGM image, padded to the next power of two in each
+// dimension with the given edge mode. EdgeMirror reduces the artefacts caused by the
+// discontinuities at the image borders.
+func (pgm *PGM) FFT(padding EdgeMode) *Spectrum{
+	s := &Spectrum{
+		Width:       nextPowerOfTwo(pgm.Width),
+		Height:      nextPowerOfTwo(pgm.Height),
+		ImageWidth:  pgm.Width,
+		ImageHeight: pgm.Height,
+		Max:         pgm.Max,
+	}
+	s.Data = make([]complex128, s.Width*s.Height)
+	if pgm.Width == 0 || pgm.Height == 0 {
+		return s
+	}
+	for y := 0; y < s.Height; y++ {
+		sy, ok := edgeIndex(y, pgm.Height, padding)
+		if !ok {
+			continue
+		}
+		for x := 0; x < s.Width; x++ {
+			if sx, ok := edgeIndex(x, pgm.Width, padding); ok {
+				s.Data[y*s.Width+x] = complex(float64(pgm.Data[sy][sx]), 0)
+			}
+		}
+	}
+	fft2D(s.Data, s.Width, s.Height, false)
+	return s
+}
+
+// ToPGM returns the inverse transform of the spectrum, cropped to the size of the
+// original image and clamped to its Max.
+func (s *Spectrum) ToPGM() *PGM{
+	data := append([]complex128(nil), s.Data...)
+	fft2D(data, s.Width, s.Height, true)
+	pgm := newPGM(s.ImageWidth, s.ImageHeight, s.Max)
+	for y := 0; y < s.ImageHeight; y++ {
+		for x := 0; x < s.ImageWidth; x++ {
+			pgm.Data[y][x] = clampToMax(real(data[y*s.Width+x]), s.Max)
+		}
+	}
+	return pgm
+}
+
+// shiftedPGM returns a PGM image with a max value of 255 of f applied to each
+// coefficient, with the zero frequency moved to the center and values stretched to 0..255.
+func (s *Spectrum) shiftedPGM(f func(c complex128) float64) *PGM{
+	pgm := newPGM(s.Width, s.Height, 255)
+	values := make([]float64, len(s.Data))
+	lowest, highest := math.Inf(1), math.Inf(-1)
+	for i, c := range s.Data {
+		values[i] = f(c)
+		lowest, highest = math.Min(lowest, values[i]), math.Max(highest, values[i])
+	}
+	if highest == lowest {
+		return pgm
+	}
+	for y := 0; y < s.Height; y++ {
+		for x := 0; x < s.Width; x++ {
+			v := values[((y+s.Height/2)%s.Height)*s.Width+(x+s.Width/2)%s.Width]
+			pgm.Data[y][x] = clampToMax((v-lowest)/(highest-lowest)*255, 255)
+		}
+	}
+	return pgm
+}
+
+// Magnitude returns the logarithm of the magnitude of the spectrum as a centered PGM
+// image, where periodic patterns show up as bright symmetric spots.
+func (s *Spectrum) Magnitude() *PGM{
+	return s.shiftedPGM(func(c complex128) float64{
+		return math.Log1p(cmplx.Abs(c))
+	})
+}
+
+// Phase returns the phase of the spectrum, from -pi to pi, as a centered PGM image.
+func (s *Spectrum) Phase() *PGM{
+	return s.shiftedPGM(func(c complex128) float64{
+		if c == 0 {
+			return 0
+		}
+		return cmplx.Phase(c)
+	})
+}
+
+// frequency returns the signed frequency, in cycles per pixel, of index k of n samples.
+func frequency(k, n int) float64{
+	if k >= (n+1)/2 {
+		k -= n
+	}
+	return float64(k) / float64(n)
+}
+
+// apply multiplies each coefficient by the transfer function h of its frequency (u, v).
+func (s *Spectrum) apply(h func(u, v float64) float64){
+	for y := 0; y < s.Height; y++ {
+		v := frequency(y, s.Height)
+		for x := 0; x < s.Width; x++ {
+			s.Data[y*s.Width+x] *= complex(h(frequency(x, s.Width), v), 0)
+		}
+	}
+}
+
+// lowPass returns the gain of a low-pass filter at distance d from its center.
+func lowPass(filter FrequencyFilter, d, cutoff float64) float64{
+	switch filter {
+	case FilterGaussian:
+		return math.Exp(-d * d / (2 * cutoff * cutoff))
+	case FilterButterworth:
+		return 1 / (1 + math.Pow(d/cutoff, 4))
+	default:
+		if d <= cutoff {
+			return 1
+		}
+		return 0
+	}
+}
+
+// LowPass keeps the frequencies below cutoff, in cycles per pixel (0 to 0.5), which blurs the image.
+func (s *Spectrum) LowPass(cutoff float64, filter FrequencyFilter){
+	s.apply(func(u, v float64) float64{
+		return lowPass(filter, math.Hypot(u, v), cutoff)
+	})
+}
+
+// HighPass keeps the frequencies above cutoff, in cycles per pixel, which keeps edges
+// and removes the mean brightness.
+func (s *Spectrum) HighPass(cutoff float64, filter FrequencyFilter){
+	s.apply(func(u, v float64) float64{
+		return 1 - lowPass(filter, math.Hypot(u, v), cutoff)
+	})
+}
+
+// BandStop removes the frequencies within width/2 of center, in cycles per pixel, in
+// every direction.
+func (s *Spectrum) BandStop(center, width float64, filter FrequencyFilter){
+	s.apply(func(u, v float64) float64{
+		d := math.Hypot(u, v)
+		switch filter {
+		case FilterGaussian:
+			if d == 0 {
+				return 1
+			}
+			r := (d*d - center*center) / (d * width)
+			return 1 - math.Exp(-r*r)
+		case FilterButterworth:
+			if d*d == center*center {
+				return 0
+			}
+			return 1 / (1 + math.Pow(d*width/(d*d-center*center), 4))
+		default:
+			if math.Abs(d-center) <= width/2 {
+				return 0
+			}
+			return 1
+		}
+	})
+}
+
+// Notch removes the frequencies within radius of (u, v) and of its symmetric (-u, -v),
+// in cycles per pixel, which removes a periodic pattern such as scanning lines. The
+// frequency of a spot of the Magnitude image at (x, y) is ((x-Width/2)/Width, (y-Height/2)/Height).
+func (s *Spectrum) Notch(u, v, radius float64, filter FrequencyFilter){
+	s.apply(func(fu, fv float64) float64{
+		return (1 - lowPass(filter, math.Hypot(fu-u, fv-v), radius)) * (1 - lowPass(filter, math.Hypot(fu+u, fv+v), radius))
+	})
+}
+
+// ApplyFrequencyFilter filters the PGM image in the frequency domain: f modifies the
+// spectrum of the image, padded with EdgeMirror, and the result replaces the image.
+func (pgm *PGM) ApplyFrequencyFilter(f func(s *Spectrum)){
+	s := pgm.FFT(EdgeMirror)
+	f(s)
+	pgm.Data = s.ToPGM().Data
+}
\ No newline at end of file
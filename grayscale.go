@@ -0,0 +1,89 @@
+package netpbm
+
+import "math"
+
+// GrayscaleMethod selects how PPM.ToPGMWith computes the gray level of a pixel.
+type GrayscaleMethod int
+
+const (
+	// GrayscaleRec601 uses the Rec. 601 luma 0.299 R + 0.587 G + 0.114 B.
+	GrayscaleRec601 GrayscaleMethod = iota
+	// GrayscaleRec709 uses the Rec. 709 luma 0.2126 R + 0.7152 G + 0.0722 B.
+	GrayscaleRec709
+	// GrayscaleLinear computes the Rec. 709 luminance on linear light values,
+	// decoding and encoding with the sRGB transfer function.
+	GrayscaleLinear
+	// GrayscaleLightness uses the CIE L* lightness, scaled from 0..100 to 0..Max.
+	GrayscaleLightness
+	// GrayscaleDesaturate uses the mean of the largest and smallest channel.
+	GrayscaleDesaturate
+	// GrayscaleAverage uses the mean of the three channels.
+	GrayscaleAverage
+	// GrayscaleRed keeps the red channel only.
+	GrayscaleRed
+	// GrayscaleGreen keeps the green channel only.
+	GrayscaleGreen
+	// GrayscaleBlue keeps the blue channel only.
+	GrayscaleBlue
+)
+
+// ToPGMWith converts the PPM image to PGM using the given method.
+func (ppm *PPM) ToPGMWith(method GrayscaleMethod) *PGM{
+	pgm := newPGM(ppm.Width, ppm.Height, ppm.Max)
+	maxValue := float64(ppm.Max)
+	for i := 0; i < ppm.Height; i++ {
+		for j := 0; j < ppm.Width; j++ {
+			p := ppm.Data[i][j]
+			r, g, b := float64(p.R), float64(p.G), float64(p.B)
+			var v float64
+			switch method {
+			case GrayscaleRec709:
+				v = 0.2126*r + 0.7152*g + 0.0722*b
+			case GrayscaleLinear:
+				y := 0.2126*srgbToLinear(r/maxValue) + 0.7152*srgbToLinear(g/maxValue) + 0.0722*srgbToLinear(b/maxValue)
+				v = linearToSrgb(y) * maxValue
+			case GrayscaleLightness:
+				y := 0.2126*srgbToLinear(r/maxValue) + 0.7152*srgbToLinear(g/maxValue) + 0.0722*srgbToLinear(b/maxValue)
+				v = lightness(y) / 100 * maxValue
+			case GrayscaleDesaturate:
+				v = (math.Max(r, math.Max(g, b)) + math.Min(r, math.Min(g, b))) / 2
+			case GrayscaleAverage:
+				v = (r + g + b) / 3
+			case GrayscaleRed:
+				v = r
+			case GrayscaleGreen:
+				v = g
+			case GrayscaleBlue:
+				v = b
+			default:
+				v = 0.299*r + 0.587*g + 0.114*b
+			}
+			pgm.Data[i][j] = clampToMax(v, ppm.Max)
+		}
+	}
+	return pgm
+}
+
+// srgbToLinear decodes a sRGB value in [0, 1] to linear light.
+func srgbToLinear(v float64) float64{
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// linearToSrgb encodes a linear light value in [0, 1] to sRGB.
+func linearToSrgb(v float64) float64{
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// lightness returns the CIE L* (0 to 100) of a relative luminance in [0, 1].
+func lightness(y float64) float64{
+	if y > 216.0/24389 {
+		return 116*math.Cbrt(y) - 16
+	}
+	return y * 24389 / 27
+}
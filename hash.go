@@ -0,0 +1,252 @@
+package netpbm
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+// HashMethod selects the perceptual hash computed by PGM.Hash and PPM.Hash.
+type HashMethod int
+
+const (
+	// AverageHash sets the bits of the 8x8 reduced image brighter than its mean.
+	AverageHash HashMethod = iota
+	// DifferenceHash sets the bits where the 9x8 reduced image gets brighter from left to right.
+	DifferenceHash
+	// PerceptualHash sets the bits of the 8x8 lowest frequencies of the discrete cosine
+	// transform of the 32x32 reduced image that are above their median.
+	PerceptualHash
+	// WaveletHash sets the bits of the 8x8 approximation band of the Haar wavelet
+	// transform of the 64x64 reduced image, without its mean, that are above their median.
+	WaveletHash
+)
+
+// Hash is a 64 bit perceptual hash. Similar images have hashes at a small Hamming distance.
+type Hash uint64
+
+// Distance returns the Hamming distance between two hashes, the number of differing bits.
+func (h Hash) Distance(other Hash) int{
+	return bits.OnesCount64(uint64(h ^ other))
+}
+
+// String returns the hash as 16 hexadecimal digits.
+func (h Hash) String() string{
+	return fmt.Sprintf("%016x", uint64(h))
+}
+
+// resizePlane resamples a plane to newWidth x newHeight by averaging the source area
+// covered by each destination pixel.
+func resizePlane(plane []float64, width, height, newWidth, newHeight int) []float64{
+	// weights returns the source pixels covered by destination pixel i and their overlaps
+	weights := func(i, n, newN int) (first int, overlaps []float64){
+		start := float64(i) * float64(n) / float64(newN)
+		end := float64(i+1) * float64(n) / float64(newN)
+		first = int(start)
+		for s := first; float64(s) < end && s < n; s++ {
+			overlaps = append(overlaps, math.Min(end, float64(s+1))-math.Max(start, float64(s)))
+		}
+		return first, overlaps
+	}
+	out := make([]float64, newWidth*newHeight)
+	for y := 0; y < newHeight; y++ {
+		y0, wy := weights(y, height, newHeight)
+		for x := 0; x < newWidth; x++ {
+			x0, wx := weights(x, width, newWidth)
+			sum, total := 0.0, 0.0
+			for dy, fy := range wy {
+				for dx, fx := range wx {
+					sum += plane[(y0+dy)*width+x0+dx] * fy * fx
+					total += fy * fx
+				}
+			}
+			out[y*newWidth+x] = sum / total
+		}
+	}
+	return out
+}
+
+// hashBits returns the hash whose bit i, from the most significant, is set if set(i) is true.
+func hashBits(set func(i int) bool) Hash{
+	var h Hash
+	for i := 0; i < 64; i++ {
+		h <<= 1
+		if set(i) {
+			h |= 1
+		}
+	}
+	return h
+}
+
+// median returns the median of the values.
+func median(values []float64) float64{
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// dct2D returns the 2D DCT-II of an n x n plane.
+func dct2D(plane []float64, n int) []float64{
+	cos := make([]float64, n*n)
+	for k := 0; k < n; k++ {
+		for i := 0; i < n; i++ {
+			cos[k*n+i] = math.Cos(math.Pi * float64(k) * (2*float64(i) + 1) / float64(2*n))
+		}
+	}
+	rows := make([]float64, n*n)
+	for y := 0; y < n; y++ {
+		for k := 0; k < n; k++ {
+			sum := 0.0
+			for x := 0; x < n; x++ {
+				sum += plane[y*n+x] * cos[k*n+x]
+			}
+			rows[y*n+k] = sum
+		}
+	}
+	out := make([]float64, n*n)
+	for x := 0; x < n; x++ {
+		for k := 0; k < n; k++ {
+			sum := 0.0
+			for y := 0; y < n; y++ {
+				sum += rows[y*n+x] * cos[k*n+y]
+			}
+			out[k*n+x] = sum
+		}
+	}
+	return out
+}
+
+// haarApproximation applies levels steps of the 2D Haar wavelet transform to an n x n
+// plane and returns its approximation band, of size n>>levels.
+func haarApproximation(plane []float64, n, levels int) []float64{
+	for ; levels > 0; levels-- {
+		half := n / 2
+		next := make([]float64, half*half)
+		for y := 0; y < half; y++ {
+			for x := 0; x < half; x++ {
+				next[y*half+x] = (plane[2*y*n+2*x] + plane[2*y*n+2*x+1] + plane[(2*y+1)*n+2*x] + plane[(2*y+1)*n+2*x+1]) / 2
+			}
+		}
+		plane, n = next, half
+	}
+	return plane
+}
+
+// Hash returns the perceptual hash of the PGM image.
+func (pgm *PGM) Hash(method HashMethod) Hash{
+	if pgm.Width == 0 || pgm.Height == 0 {
+		return 0
+	}
+	plane := pgm.plane()
+	switch method {
+	case DifferenceHash:
+		small := resizePlane(plane, pgm.Width, pgm.Height, 9, 8)
+		return hashBits(func(i int) bool{
+			y, x := i/8, i%8
+			return small[y*9+x+1] > small[y*9+x]
+		})
+	case PerceptualHash:
+		coefficients := dct2D(resizePlane(plane, pgm.Width, pgm.Height, 32, 32), 32)
+		low := make([]float64, 64)
+		for i := range low {
+			low[i] = coefficients[(i/8)*32+i%8]
+		}
+		m := median(low)
+		return hashBits(func(i int) bool{
+			return low[i] > m
+		})
+	case WaveletHash:
+		band := haarApproximation(resizePlane(plane, pgm.Width, pgm.Height, 64, 64), 64, 3)
+		mean := 0.0
+		for _, v := range band {
+			mean += v / 64
+		}
+		for i := range band {
+			band[i] -= mean
+		}
+		m := median(band)
+		return hashBits(func(i int) bool{
+			return band[i] > m
+		})
+	default:
+		small := resizePlane(plane, pgm.Width, pgm.Height, 8, 8)
+		mean := 0.0
+		for _, v := range small {
+			mean += v / 64
+		}
+		return hashBits(func(i int) bool{
+			return small[i] > mean
+		})
+	}
+}
+
+// Hash returns the perceptual hash of the grayscale version of the PPM image.
+func (ppm *PPM) Hash(method HashMethod) Hash{
+	return ppm.ToPGM().Hash(method)
+}
+
+// HashIndex is a BK-tree of hashes for finding near-duplicates without comparing every pair.
+// The zero value is an empty index.
+type HashIndex struct {
+	root *hashNode
+}
+
+// hashNode is a node of a HashIndex; children are keyed by their distance to the node.
+type hashNode struct {
+	hash     Hash
+	ids      []int
+	children map[int]*hashNode
+}
+
+// Add adds a hash to the index with an identifier, such as a frame number.
+func (index *HashIndex) Add(hash Hash, id int){
+	if index.root == nil {
+		index.root = &hashNode{hash: hash, ids: []int{id}}
+		return
+	}
+	node := index.root
+	for {
+		d := node.hash.Distance(hash)
+		if d == 0 {
+			node.ids = append(node.ids, id)
+			return
+		}
+		child, ok := node.children[d]
+		if !ok {
+			if node.children == nil {
+				node.children = make(map[int]*hashNode)
+			}
+			node.children[d] = &hashNode{hash: hash, ids: []int{id}}
+			return
+		}
+		node = child
+	}
+}
+
+// Search returns the identifiers of the hashes within maxDistance of hash.
+func (index *HashIndex) Search(hash Hash, maxDistance int) []int{
+	var ids []int
+	if index.root == nil {
+		return ids
+	}
+	stack := []*hashNode{index.root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		d := node.hash.Distance(hash)
+		if d <= maxDistance {
+			ids = append(ids, node.ids...)
+		}
+		for distance, child := range node.children {
+			if distance >= d-maxDistance && distance <= d+maxDistance {
+				stack = append(stack, child)
+			}
+		}
+	}
+	return ids
+}
\ No newline at end of file
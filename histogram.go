@@ -0,0 +1,257 @@
+package netpbm
+
+import "math"
+
+// Histogram holds the distribution of the values of an image channel.
+// Counts and Cumulative have one entry per value from 0 to the image Max.
+type Histogram struct {
+	Counts       []int
+	Cumulative   []int
+	Total        int
+	Min, Max     int
+	Median, Mode int
+	Mean, StdDev float64
+}
+
+// newHistogram computes the histogram of a channel whose values go from 0 to max.
+func newHistogram(data [][]uint8, max int) *Histogram{
+	bins := min(max, 255) + 1
+	if bins <= 0 {
+		bins = 256
+	}
+	h := Histogram{Counts: make([]int, bins)}
+	for _, row := range data {
+		for _, v := range row {
+			if int(v) >= len(h.Counts) {
+				h.Counts = append(h.Counts, make([]int, int(v)+1-len(h.Counts))...)
+			}
+			h.Counts[v]++
+		}
+	}
+	h.Cumulative = make([]int, len(h.Counts))
+	h.Min = -1
+	sum, sumSq := 0.0, 0.0
+	for v, n := range h.Counts {
+		h.Total += n
+		h.Cumulative[v] = h.Total
+		if n > 0 {
+			if h.Min < 0 {
+				h.Min = v
+			}
+			h.Max = v
+		}
+		if n > h.Counts[h.Mode] {
+			h.Mode = v
+		}
+		sum += float64(v * n)
+		sumSq += float64(v * v * n)
+	}
+	if h.Total == 0 {
+		h.Min = 0
+		return &h
+	}
+	h.Mean = sum / float64(h.Total)
+	h.StdDev = math.Sqrt(math.Max(sumSq/float64(h.Total)-h.Mean*h.Mean, 0))
+	h.Median = h.Percentile(50)
+	return &h
+}
+
+// CDF returns the cumulative distribution normalized to [0, 1].
+func (h *Histogram) CDF() []float64{
+	cdf := make([]float64, len(h.Cumulative))
+	if h.Total == 0 {
+		return cdf
+	}
+	for v, n := range h.Cumulative {
+		cdf[v] = float64(n) / float64(h.Total)
+	}
+	return cdf
+}
+
+// Percentile returns the smallest value such that p percent of the values are lower or equal.
+func (h *Histogram) Percentile(p float64) int{
+	target := p / 100 * float64(h.Total)
+	for v, n := range h.Cumulative {
+		if float64(n) >= target && n > 0 {
+			return v
+		}
+	}
+	return len(h.Cumulative) - 1
+}
+
+// Histogram returns the histogram of the PGM image.
+func (pgm *PGM) Histogram() *Histogram{
+	return newHistogram(pgm.Data, pgm.Max)
+}
+
+// Histogram returns the histograms of the R, G and B channels of the PPM image.
+func (ppm *PPM) Histogram() (r, g, b *Histogram){
+	rc, gc, bc := ppm.channels()
+	return newHistogram(rc, ppm.Max), newHistogram(gc, ppm.Max), newHistogram(bc, ppm.Max)
+}
+
+// equalizeLUT returns the lookup table spreading the histogram over 0..max.
+func equalizeLUT(h *Histogram, max int) []uint8{
+	lut := make([]uint8, len(h.Counts))
+	cdfMin := 0
+	for _, n := range h.Cumulative {
+		if n > 0 {
+			cdfMin = n
+			break
+		}
+	}
+	if h.Total == cdfMin {
+		for v := range lut {
+			lut[v] = uint8(v)
+		}
+		return lut
+	}
+	for v, n := range h.Cumulative {
+		lut[v] = clampToMax(float64(n-cdfMin)/float64(h.Total-cdfMin)*float64(max), max)
+	}
+	return lut
+}
+
+// matchLUT returns the lookup table mapping the source distribution onto the reference one.
+func matchLUT(source, reference *Histogram) []uint8{
+	src, ref := source.CDF(), reference.CDF()
+	lut := make([]uint8, len(src))
+	r := 0
+	for v := range src {
+		for r < len(ref)-1 && ref[r] < src[v] {
+			r++
+		}
+		lut[v] = uint8(r)
+	}
+	return lut
+}
+
+// Equalize spreads the values of the PGM image so that its histogram is as flat as possible.
+func (pgm *PGM) Equalize(){
+	pgm.ApplyLUT(equalizeLUT(pgm.Histogram(), pgm.Max))
+}
+
+// MatchHistogram remaps the values of the PGM image so that its histogram matches the reference one.
+func (pgm *PGM) MatchHistogram(reference *PGM){
+	pgm.ApplyLUT(matchLUT(pgm.Histogram(), reference.Histogram()))
+}
+
+// CLAHE applies contrast limited adaptive histogram equalization to the PGM image.
+// The image is divided in tilesX x tilesY tiles, and each tile histogram is clipped at
+// clipLimit times the mean bin count before equalization (0 disables clipping, and values
+// close to 1 barely change the image). Tile mappings are bilinearly interpolated between tile centers.
+func (pgm *PGM) CLAHE(tilesX, tilesY int, clipLimit float64){
+	if pgm.Width == 0 || pgm.Height == 0 || pgm.Max <= 0 {
+		return
+	}
+	tilesX = max(1, min(tilesX, pgm.Width))
+	tilesY = max(1, min(tilesY, pgm.Height))
+	bins := min(pgm.Max, 255) + 1
+
+	// Mapping of each tile
+	luts := make([][]float64, tilesX*tilesY)
+	for ty := 0; ty < tilesY; ty++ {
+		y0, y1 := ty*pgm.Height/tilesY, (ty+1)*pgm.Height/tilesY
+		for tx := 0; tx < tilesX; tx++ {
+			x0, x1 := tx*pgm.Width/tilesX, (tx+1)*pgm.Width/tilesX
+			counts := make([]float64, bins)
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					counts[min(int(pgm.Data[y][x]), bins-1)]++
+				}
+			}
+			total := float64((y1 - y0) * (x1 - x0))
+			if clipLimit > 0 {
+				limit := math.Max(1, clipLimit*total/float64(bins))
+				excess := 0.0
+				for v := range counts {
+					if counts[v] > limit {
+						excess += counts[v] - limit
+						counts[v] = limit
+					}
+				}
+				for v := range counts {
+					counts[v] += excess / float64(bins)
+				}
+			}
+			lut := make([]float64, bins)
+			cumulative := 0.0
+			for v := range counts {
+				cumulative += counts[v]
+				lut[v] = cumulative / total * float64(pgm.Max)
+			}
+			luts[ty*tilesX+tx] = lut
+		}
+	}
+
+	// Interpolate between the four closest tile centers
+	tileW, tileH := float64(pgm.Width)/float64(tilesX), float64(pgm.Height)/float64(tilesY)
+	for y := 0; y < pgm.Height; y++ {
+		fy := (float64(y)+0.5)/tileH - 0.5
+		ty0 := int(math.Floor(fy))
+		wy := fy - float64(ty0)
+		ty1 := min(ty0+1, tilesY-1)
+		ty0 = max(ty0, 0)
+		for x := 0; x < pgm.Width; x++ {
+			fx := (float64(x)+0.5)/tileW - 0.5
+			tx0 := int(math.Floor(fx))
+			wx := fx - float64(tx0)
+			tx1 := min(tx0+1, tilesX-1)
+			tx0 = max(tx0, 0)
+			v := min(int(pgm.Data[y][x]), bins-1)
+			top := (1-wx)*luts[ty0*tilesX+tx0][v] + wx*luts[ty0*tilesX+tx1][v]
+			bottom := (1-wx)*luts[ty1*tilesX+tx0][v] + wx*luts[ty1*tilesX+tx1][v]
+			pgm.Data[y][x] = clampToMax((1-wy)*top+wy*bottom, pgm.Max)
+		}
+	}
+}
+
+// applyToLuma applies f to the luma of the PPM image, keeping its chroma.
+func (ppm *PPM) applyToLuma(f func(luma *PGM)){
+	luma := newPGM(ppm.Width, ppm.Height, ppm.Max)
+	chroma := make([]YCbCr, ppm.Width*ppm.Height)
+	for i := 0; i < ppm.Height; i++ {
+		for j := 0; j < ppm.Width; j++ {
+			c := ppm.Data[i][j].ToYCbCr(ppm.Max)
+			chroma[i*ppm.Width+j] = c
+			luma.Data[i][j] = clampToMax(c.Y*float64(ppm.Max), ppm.Max)
+		}
+	}
+	f(luma)
+	for i := 0; i < ppm.Height; i++ {
+		for j := 0; j < ppm.Width; j++ {
+			c := chroma[i*ppm.Width+j]
+			c.Y = float64(luma.Data[i][j]) / float64(ppm.Max)
+			ppm.Data[i][j] = c.ToPixel(ppm.Max)
+		}
+	}
+}
+
+// Equalize equalizes the luma of the PPM image, keeping its chroma.
+func (ppm *PPM) Equalize(){
+	ppm.applyToLuma(func(luma *PGM){
+		luma.Equalize()
+	})
+}
+
+// MatchHistogram remaps each channel of the PPM image so that its histogram matches
+// the one of the same channel of the reference.
+func (ppm *PPM) MatchHistogram(reference *PPM){
+	r, g, b := ppm.Histogram()
+	refR, refG, refB := reference.Histogram()
+	lutR, lutG, lutB := matchLUT(r, refR), matchLUT(g, refG), matchLUT(b, refB)
+	for i := 0; i < ppm.Height; i++ {
+		for j := 0; j < ppm.Width; j++ {
+			p := ppm.Data[i][j]
+			ppm.Data[i][j] = Pixel{lutR[min(int(p.R), len(lutR)-1)], lutG[min(int(p.G), len(lutG)-1)], lutB[min(int(p.B), len(lutB)-1)]}
+		}
+	}
+}
+
+// CLAHE applies contrast limited adaptive histogram equalization to the luma of the
+// PPM image, keeping its chroma. See PGM.CLAHE.
+func (ppm *PPM) CLAHE(tilesX, tilesY int, clipLimit float64){
+	ppm.applyToLuma(func(luma *PGM){
+		luma.CLAHE(tilesX, tilesY, clipLimit)
+	})
+}
\ No newline at end of file
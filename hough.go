@@ -0,0 +1,360 @@
+package netpbm
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// HoughLine is a line in normal form, the points (x, y) with x*cos(Theta) + y*sin(Theta) = Rho,
+// and the number of edge pixels that voted for it.
+type HoughLine struct {
+	Rho, Theta float64
+	Votes      int
+}
+
+// LineSegment is a segment found by the probabilistic Hough transform.
+type LineSegment struct {
+	Start, End Point
+}
+
+// HoughCircle is a circle and the number of edge pixels that voted for it.
+type HoughCircle struct {
+	Center Point
+	Radius int
+	Votes  int
+}
+
+// houghTables returns the cosines and sines of thetaSteps angles covering [0, pi).
+func houghTables(thetaSteps int) (cos, sin []float64){
+	cos, sin = make([]float64, thetaSteps), make([]float64, thetaSteps)
+	for t := range cos {
+		theta := math.Pi * float64(t) / float64(thetaSteps)
+		cos[t], sin[t] = math.Cos(theta), math.Sin(theta)
+	}
+	return cos, sin
+}
+
+// edgePoints returns the positions of the set pixels of the PBM image in scan order.
+func (pbm *PBM) edgePoints() []Point{
+	var points []Point
+	for y := 0; y < pbm.Height; y++ {
+		for x := 0; x < pbm.Width; x++ {
+			if pbm.Data[y][x] {
+				points = append(points, Point{x, y})
+			}
+		}
+	}
+	return points
+}
+
+// edgeMap returns a PBM image where the non-zero pixels of the PGM image are set.
+func (pgm *PGM) edgeMap() *PBM{
+	pbm := newPBM(pgm.Width, pgm.Height)
+	for y := 0; y < pgm.Height; y++ {
+		for x := 0; x < pgm.Width; x++ {
+			pbm.Data[y][x] = pgm.Data[y][x] > 0
+		}
+	}
+	return pbm
+}
+
+// HoughLines returns the lines with at least threshold votes among the set pixels of
+// the PBM edge map, strongest first, using thetaSteps angles and a distance resolution
+// of one pixel. Only local maxima of the accumulator are kept; maxLines limits the
+// number of lines returned (0 means no limit).
+func (pbm *PBM) HoughLines(thetaSteps, threshold, maxLines int) []HoughLine{
+	if thetaSteps <= 0 {
+		thetaSteps = 180
+	}
+	cos, sin := houghTables(thetaSteps)
+	diagonal := int(math.Ceil(math.Hypot(float64(pbm.Width), float64(pbm.Height))))
+	rhos := 2*diagonal + 1
+	acc := make([]int, thetaSteps*rhos)
+	for _, p := range pbm.edgePoints() {
+		for t := 0; t < thetaSteps; t++ {
+			r := int(math.Round(float64(p.X)*cos[t]+float64(p.Y)*sin[t])) + diagonal
+			acc[t*rhos+r]++
+		}
+	}
+
+	var lines []HoughLine
+	for t := 0; t < thetaSteps; t++ {
+		for r := 0; r < rhos; r++ {
+			votes := acc[t*rhos+r]
+			if votes < max(threshold, 1) {
+				continue
+			}
+			peak := true
+			for dt := -1; dt <= 1 && peak; dt++ {
+				for dr := -1; dr <= 1; dr++ {
+					nt, nr := t+dt, r+dr
+					if (dt == 0 && dr == 0) || nt < 0 || nt >= thetaSteps || nr < 0 || nr >= rhos {
+						continue
+					}
+					// Ties are broken in scan order so that flat peaks give a single line
+					if n := acc[nt*rhos+nr]; n > votes || (n == votes && nt*rhos+nr < t*rhos+r) {
+						peak = false
+						break
+					}
+				}
+			}
+			if peak {
+				lines = append(lines, HoughLine{float64(r - diagonal), math.Pi * float64(t) / float64(thetaSteps), votes})
+			}
+		}
+	}
+	sort.SliceStable(lines, func(i, j int) bool{
+		return lines[i].Votes > lines[j].Votes
+	})
+	if maxLines > 0 && len(lines) > maxLines {
+		lines = lines[:maxLines]
+	}
+	return lines
+}
+
+// HoughLines returns the lines of the PGM edge map, where non-zero pixels are edges.
+// See PBM.HoughLines.
+func (pgm *PGM) HoughLines(thetaSteps, threshold, maxLines int) []HoughLine{
+	return pgm.edgeMap().HoughLines(thetaSteps, threshold, maxLines)
+}
+
+// Endpoints returns the two points where the line crosses the border of an image of
+// the given size, so that it can be drawn with PPM.DrawLine. ok is false if the line
+// does not cross the image.
+func (line HoughLine) Endpoints(width, height int) (p1, p2 Point, ok bool){
+	cos, sin := math.Cos(line.Theta), math.Sin(line.Theta)
+	maxX, maxY := float64(width-1), float64(height-1)
+	var points []Point
+	addPoint := func(x, y float64){
+		if x < -0.5 || y < -0.5 || x > maxX+0.5 || y > maxY+0.5 {
+			return
+		}
+		p := Point{int(math.Round(math.Max(0, math.Min(maxX, x)))), int(math.Round(math.Max(0, math.Min(maxY, y))))}
+		for _, q := range points {
+			if q == p {
+				return
+			}
+		}
+		points = append(points, p)
+	}
+	if math.Abs(sin) > 1e-9 {
+		addPoint(0, line.Rho/sin)
+		addPoint(maxX, (line.Rho-maxX*cos)/sin)
+	}
+	if math.Abs(cos) > 1e-9 {
+		addPoint(line.Rho/cos, 0)
+		addPoint((line.Rho-maxY*sin)/cos, maxY)
+	}
+	if len(points) == 0 {
+		return Point{}, Point{}, false
+	}
+	// Keep the two points farthest apart
+	p1, p2 = points[0], points[0]
+	best := -1
+	for i := range points {
+		for j := i + 1; j < len(points); j++ {
+			if d := (points[i].X-points[j].X)*(points[i].X-points[j].X) + (points[i].Y-points[j].Y)*(points[i].Y-points[j].Y); d > best {
+				p1, p2, best = points[i], points[j], d
+			}
+		}
+	}
+	return p1, p2, true
+}
+
+// HoughLinesP returns the segments found by the progressive probabilistic Hough
+// transform of Matas et al. on the set pixels of the PBM edge map. Pixels are visited
+// in a random but reproducible order; when a line reaches threshold votes the edge
+// pixels along it are followed in both directions, bridging gaps of up to maxGap
+// pixels, then removed from the accumulator. Segments shorter than minLength are dropped.
+func (pbm *PBM) HoughLinesP(thetaSteps, threshold, minLength, maxGap int) []LineSegment{
+	if thetaSteps <= 0 {
+		thetaSteps = 180
+	}
+	cos, sin := houghTables(thetaSteps)
+	diagonal := int(math.Ceil(math.Hypot(float64(pbm.Width), float64(pbm.Height))))
+	rhos := 2*diagonal + 1
+	acc := make([]int, thetaSteps*rhos)
+	vote := func(p Point, delta int){
+		for t := 0; t < thetaSteps; t++ {
+			r := int(math.Round(float64(p.X)*cos[t]+float64(p.Y)*sin[t])) + diagonal
+			acc[t*rhos+r] += delta
+		}
+	}
+
+	mask := newPBM(pbm.Width, pbm.Height)
+	voted := newPBM(pbm.Width, pbm.Height)
+	points := pbm.edgePoints()
+	for _, p := range points {
+		mask.Data[p.Y][p.X] = true
+	}
+	random := rand.New(rand.NewSource(1))
+	random.Shuffle(len(points), func(i, j int){
+		points[i], points[j] = points[j], points[i]
+	})
+
+	var segments []LineSegment
+	for _, p := range points {
+		if !mask.Data[p.Y][p.X] {
+			continue
+		}
+		vote(p, 1)
+		voted.Data[p.Y][p.X] = true
+		best, bestTheta := 0, 0
+		for t := 0; t < thetaSteps; t++ {
+			r := int(math.Round(float64(p.X)*cos[t]+float64(p.Y)*sin[t])) + diagonal
+			if acc[t*rhos+r] > best {
+				best, bestTheta = acc[t*rhos+r], t
+			}
+		}
+		if best < max(threshold, 1) {
+			continue
+		}
+
+		// Step one pixel along the dominant axis of the line direction (-sin, cos)
+		dx, dy := -sin[bestTheta], cos[bestTheta]
+		scale := math.Max(math.Abs(dx), math.Abs(dy))
+		dx, dy = dx/scale, dy/scale
+		var ends [2]Point
+		for k, sign := range []float64{1, -1} {
+			ends[k] = p
+			gap := 0
+			for step := 1; ; step++ {
+				x := int(math.Round(float64(p.X) + sign*dx*float64(step)))
+				y := int(math.Round(float64(p.Y) + sign*dy*float64(step)))
+				if x < 0 || y < 0 || x >= pbm.Width || y >= pbm.Height {
+					break
+				}
+				if mask.Data[y][x] {
+					ends[k], gap = Point{x, y}, 0
+				} else if gap++; gap > maxGap {
+					break
+				}
+			}
+		}
+
+		// Remove the pixels of the segment, with a one pixel margin across it, from the
+		// mask and the accumulator
+		across := Point{0, 1}
+		if math.Abs(dy) > math.Abs(dx) {
+			across = Point{1, 0}
+		}
+		steps := max(absInt(ends[0].X-ends[1].X), absInt(ends[0].Y-ends[1].Y))
+		for step := 0; step <= steps; step++ {
+			f := 0.0
+			if steps > 0 {
+				f = float64(step) / float64(steps)
+			}
+			cx := int(math.Round(float64(ends[1].X) + f*float64(ends[0].X-ends[1].X)))
+			cy := int(math.Round(float64(ends[1].Y) + f*float64(ends[0].Y-ends[1].Y)))
+			for k := -1; k <= 1; k++ {
+				x, y := cx+k*across.X, cy+k*across.Y
+				if x < 0 || y < 0 || x >= pbm.Width || y >= pbm.Height || !mask.Data[y][x] {
+					continue
+				}
+				mask.Data[y][x] = false
+				if voted.Data[y][x] {
+					vote(Point{x, y}, -1)
+				}
+			}
+		}
+		if float64(minLength) <= math.Hypot(float64(ends[0].X-ends[1].X), float64(ends[0].Y-ends[1].Y)) {
+			segments = append(segments, LineSegment{ends[1], ends[0]})
+		}
+	}
+	return segments
+}
+
+// HoughLinesP returns the segments of the PGM edge map, where non-zero pixels are edges.
+// See PBM.HoughLinesP.
+func (pgm *PGM) HoughLinesP(thetaSteps, threshold, minLength, maxGap int) []LineSegment{
+	return pgm.edgeMap().HoughLinesP(thetaSteps, threshold, minLength, maxGap)
+}
+
+// circleOffsets returns the distinct offsets of the points of a circle of the given
+// radius, as drawn by PPM.DrawCircle.
+func circleOffsets(radius int) []Point{
+	seen := make(map[Point]bool)
+	var offsets []Point
+	addOffset := func(x, y int){
+		if p := (Point{x, y}); !seen[p] {
+			seen[p] = true
+			offsets = append(offsets, p)
+		}
+	}
+	x, y, d := 0, radius, 1-radius
+	for x <= y {
+		for _, p := range []Point{{x, y}, {x, -y}, {-x, y}, {-x, -y}, {y, x}, {y, -x}, {-y, x}, {-y, -x}} {
+			addOffset(p.X, p.Y)
+		}
+		if d < 0 {
+			d += 2*x + 3
+		} else {
+			d += 2*(x-y) + 5
+			y--
+		}
+		x++
+	}
+	return offsets
+}
+
+// HoughCircles returns the circles with a radius between minRadius and maxRadius
+// whose centers are inside the PBM edge map and at least threshold (0 to 1) of whose
+// points are edge pixels, strongest first. A circle whose center is closer than
+// minDistance to a stronger one is dropped.
+func (pbm *PBM) HoughCircles(minRadius, maxRadius int, threshold float64, minDistance int) []HoughCircle{
+	type candidate struct {
+		circle HoughCircle
+		score  float64
+	}
+	var candidates []candidate
+	points := pbm.edgePoints()
+	acc := make([]int, pbm.Width*pbm.Height)
+	for radius := max(minRadius, 1); radius <= maxRadius; radius++ {
+		offsets := circleOffsets(radius)
+		for i := range acc {
+			acc[i] = 0
+		}
+		for _, p := range points {
+			for _, o := range offsets {
+				x, y := p.X-o.X, p.Y-o.Y
+				if x >= 0 && y >= 0 && x < pbm.Width && y < pbm.Height {
+					acc[y*pbm.Width+x]++
+				}
+			}
+		}
+		minVotes := max(int(math.Ceil(threshold*float64(len(offsets)))), 1)
+		for y := 0; y < pbm.Height; y++ {
+			for x := 0; x < pbm.Width; x++ {
+				if votes := acc[y*pbm.Width+x]; votes >= minVotes {
+					candidates = append(candidates, candidate{HoughCircle{Point{x, y}, radius, votes}, float64(votes) / float64(len(offsets))})
+				}
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool{
+		return candidates[i].score > candidates[j].score
+	})
+
+	var circles []HoughCircle
+	for _, c := range candidates {
+		keep := true
+		for _, kept := range circles {
+			dx, dy := c.circle.Center.X-kept.Center.X, c.circle.Center.Y-kept.Center.Y
+			if dx*dx+dy*dy < minDistance*minDistance || (dx == 0 && dy == 0) {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			circles = append(circles, c.circle)
+		}
+	}
+	return circles
+}
+
+// HoughCircles returns the circles of the PGM edge map, where non-zero pixels are edges.
+// See PBM.HoughCircles.
+func (pgm *PGM) HoughCircles(minRadius, maxRadius int, threshold float64, minDistance int) []HoughCircle{
+	return pgm.edgeMap().HoughCircles(minRadius, maxRadius, threshold, minDistance)
+}
\ No newline at end of file
@@ -0,0 +1,301 @@
+package netpbm
+
+// StructuringElement is the neighbourhood used by the morphological operators.
+// Its origin is the pixel at (Width/2, Height/2).
+type StructuringElement struct {
+	Data          [][]bool
+	Width, Height int
+}
+
+// newStructuringElement returns an empty structuring element of the given size.
+func newStructuringElement(width, height int) *StructuringElement{
+	data := make([][]bool, height)
+	for i := range data {
+		data[i] = make([]bool, width)
+	}
+	return &StructuringElement{Data: data, Width: width, Height: height}
+}
+
+// RectangleElement returns a width x height rectangle.
+func RectangleElement(width, height int) *StructuringElement{
+	se := newStructuringElement(width, height)
+	for i := range se.Data {
+		for j := range se.Data[i] {
+			se.Data[i][j] = true
+		}
+	}
+	return se
+}
+
+// CrossElement returns a cross whose arms are radius pixels long.
+func CrossElement(radius int) *StructuringElement{
+	se := newStructuringElement(2*radius+1, 2*radius+1)
+	for i := 0; i < se.Height; i++ {
+		se.Data[i][radius] = true
+		se.Data[radius][i] = true
+	}
+	return se
+}
+
+// DiskElement returns a disk of the given radius.
+func DiskElement(radius int) *StructuringElement{
+	se := newStructuringElement(2*radius+1, 2*radius+1)
+	for i := -radius; i <= radius; i++ {
+		for j := -radius; j <= radius; j++ {
+			se.Data[i+radius][j+radius] = i*i+j*j <= radius*radius
+		}
+	}
+	return se
+}
+
+// ElementFromPBM returns a structuring element made of the set pixels of the PBM image.
+func ElementFromPBM(pbm *PBM) *StructuringElement{
+	se := newStructuringElement(pbm.Width, pbm.Height)
+	for i := 0; i < pbm.Height; i++ {
+		copy(se.Data[i], pbm.Data[i])
+	}
+	return se
+}
+
+// offsets returns the positions of the set pixels relative to the origin.
+func (se *StructuringElement) offsets() []Point{
+	var points []Point
+	for i := 0; i < se.Height; i++ {
+		for j := 0; j < se.Width; j++ {
+			if se.Data[i][j] {
+				points = append(points, Point{j - se.Width/2, i - se.Height/2})
+			}
+		}
+	}
+	return points
+}
+
+// erodeBinary returns the erosion of data by se. Pixels outside the image have the value outside.
+func erodeBinary(data [][]bool, width, height int, se *StructuringElement, outside bool) [][]bool{
+	offsets := se.offsets()
+	out := make([][]bool, height)
+	for y := 0; y < height; y++ {
+		out[y] = make([]bool, width)
+		for x := 0; x < width; x++ {
+			value := true
+			for _, o := range offsets {
+				sx, sy := x+o.X, y+o.Y
+				var v bool
+				if sx < 0 || sy < 0 || sx >= width || sy >= height {
+					v = outside
+				} else {
+					v = data[sy][sx]
+				}
+				if !v {
+					value = false
+					break
+				}
+			}
+			out[y][x] = value
+		}
+	}
+	return out
+}
+
+// dilateBinary returns the dilation of data by se. Pixels outside the image are unset.
+func dilateBinary(data [][]bool, width, height int, se *StructuringElement) [][]bool{
+	offsets := se.offsets()
+	out := make([][]bool, height)
+	for y := 0; y < height; y++ {
+		out[y] = make([]bool, width)
+		for x := 0; x < width; x++ {
+			for _, o := range offsets {
+				sx, sy := x-o.X, y-o.Y
+				if sx >= 0 && sy >= 0 && sx < width && sy < height && data[sy][sx] {
+					out[y][x] = true
+					break
+				}
+			}
+		}
+	}
+	return out
+}
+
+// Erode erodes the set pixels of the PBM image by the structuring element.
+// Pixels outside the image are considered set, so the border does not erode.
+func (pbm *PBM) Erode(se *StructuringElement){
+	pbm.Data = erodeBinary(pbm.Data, pbm.Width, pbm.Height, se, true)
+}
+
+// Dilate dilates the set pixels of the PBM image by the structuring element.
+func (pbm *PBM) Dilate(se *StructuringElement){
+	pbm.Data = dilateBinary(pbm.Data, pbm.Width, pbm.Height, se)
+}
+
+// Open erodes then dilates the PBM image, removing details smaller than the structuring element.
+func (pbm *PBM) Open(se *StructuringElement){
+	pbm.Erode(se)
+	pbm.Dilate(se)
+}
+
+// Close dilates then erodes the PBM image, filling gaps smaller than the structuring element.
+func (pbm *PBM) Close(se *StructuringElement){
+	pbm.Dilate(se)
+	pbm.Erode(se)
+}
+
+// HitOrMiss keeps the pixels where every pixel of hit is set and every pixel of miss is unset.
+// Pixels outside the image are considered unset.
+func (pbm *PBM) HitOrMiss(hit, miss *StructuringElement){
+	inverse := make([][]bool, pbm.Height)
+	for i := range inverse {
+		inverse[i] = make([]bool, pbm.Width)
+		for j := range inverse[i] {
+			inverse[i][j] = !pbm.Data[i][j]
+		}
+	}
+	hits := erodeBinary(pbm.Data, pbm.Width, pbm.Height, hit, false)
+	misses := erodeBinary(inverse, pbm.Width, pbm.Height, miss, true)
+	for i := 0; i < pbm.Height; i++ {
+		for j := 0; j < pbm.Width; j++ {
+			pbm.Data[i][j] = hits[i][j] && misses[i][j]
+		}
+	}
+}
+
+// Thin reduces the set regions of the PBM image to one pixel wide skeletons
+// using the Zhang-Suen algorithm.
+func (pbm *PBM) Thin(){
+	at := func(x, y int) int{
+		if x < 0 || y < 0 || x >= pbm.Width || y >= pbm.Height || !pbm.Data[y][x] {
+			return 0
+		}
+		return 1
+	}
+	for changed := true; changed; {
+		changed = false
+		for step := 0; step < 2; step++ {
+			var remove []Point
+			for y := 0; y < pbm.Height; y++ {
+				for x := 0; x < pbm.Width; x++ {
+					if !pbm.Data[y][x] {
+						continue
+					}
+					// Neighbours clockwise from north
+					n := [8]int{at(x, y-1), at(x+1, y-1), at(x+1, y), at(x+1, y+1), at(x, y+1), at(x-1, y+1), at(x-1, y), at(x-1, y-1)}
+					count, transitions := 0, 0
+					for k := 0; k < 8; k++ {
+						count += n[k]
+						if n[k] == 0 && n[(k+1)%8] == 1 {
+							transitions++
+						}
+					}
+					if count < 2 || count > 6 || transitions != 1 {
+						continue
+					}
+					if step == 0 && (n[0]*n[2]*n[4] != 0 || n[2]*n[4]*n[6] != 0) {
+						continue
+					}
+					if step == 1 && (n[0]*n[2]*n[6] != 0 || n[0]*n[4]*n[6] != 0) {
+						continue
+					}
+					remove = append(remove, Point{x, y})
+				}
+			}
+			for _, p := range remove {
+				pbm.Data[p.Y][p.X] = false
+			}
+			if len(remove) > 0 {
+				changed = true
+			}
+		}
+	}
+}
+
+// FillHoles sets every unset region of the PBM image that is not connected to the border.
+func (pbm *PBM) FillHoles(){
+	outside := make([][]bool, pbm.Height)
+	for i := range outside {
+		outside[i] = make([]bool, pbm.Width)
+	}
+	var stack []Point
+	push := func(x, y int){
+		if x < 0 || y < 0 || x >= pbm.Width || y >= pbm.Height || pbm.Data[y][x] || outside[y][x] {
+			return
+		}
+		outside[y][x] = true
+		stack = append(stack, Point{x, y})
+	}
+	for x := 0; x < pbm.Width; x++ {
+		push(x, 0)
+		push(x, pbm.Height-1)
+	}
+	for y := 0; y < pbm.Height; y++ {
+		push(0, y)
+		push(pbm.Width-1, y)
+	}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		push(p.X+1, p.Y)
+		push(p.X-1, p.Y)
+		push(p.X, p.Y+1)
+		push(p.X, p.Y-1)
+	}
+	for i := 0; i < pbm.Height; i++ {
+		for j := 0; j < pbm.Width; j++ {
+			if !outside[i][j] {
+				pbm.Data[i][j] = true
+			}
+		}
+	}
+}
+
+// grayMorphology returns the minimum (erode) or maximum of data over se.
+// Pixels outside the image are ignored.
+func grayMorphology(data [][]uint8, width, height int, se *StructuringElement, erode bool) [][]uint8{
+	offsets := se.offsets()
+	out := make([][]uint8, height)
+	for y := 0; y < height; y++ {
+		out[y] = make([]uint8, width)
+		for x := 0; x < width; x++ {
+			value := data[y][x]
+			found := false
+			for _, o := range offsets {
+				var sx, sy int
+				if erode {
+					sx, sy = x+o.X, y+o.Y
+				} else {
+					sx, sy = x-o.X, y-o.Y
+				}
+				if sx < 0 || sy < 0 || sx >= width || sy >= height {
+					continue
+				}
+				v := data[sy][sx]
+				if !found || (erode && v < value) || (!erode && v > value) {
+					value = v
+					found = true
+				}
+			}
+			out[y][x] = value
+		}
+	}
+	return out
+}
+
+// Erode replaces each pixel of the PGM image with the minimum over the structuring element.
+func (pgm *PGM) Erode(se *StructuringElement){
+	pgm.Data = grayMorphology(pgm.Data, pgm.Width, pgm.Height, se, true)
+}
+
+// Dilate replaces each pixel of the PGM image with the maximum over the structuring element.
+func (pgm *PGM) Dilate(se *StructuringElement){
+	pgm.Data = grayMorphology(pgm.Data, pgm.Width, pgm.Height, se, false)
+}
+
+// Open erodes then dilates the PGM image, removing bright details smaller than the structuring element.
+func (pgm *PGM) Open(se *StructuringElement){
+	pgm.Erode(se)
+	pgm.Dilate(se)
+}
+
+// Close dilates then erodes the PGM image, removing dark details smaller than the structuring element.
+func (pgm *PGM) Close(se *StructuringElement){
+	pgm.Dilate(se)
+	pgm.Erode(se)
+}
\ No newline at end of file
@@ -13,6 +13,15 @@ type PBM struct {
 	MagicNumber   string
 }
 
+// newPBM returns an empty P1 image of the given size.
+func newPBM(width, height int) *PBM{
+	data := make([][]bool, height)
+	for i := range data {
+		data[i] = make([]bool, width)
+	}
+	return &PBM{Data: data, Width: width, Height: height, MagicNumber: "P1"}
+}
+
 // ReadPBM reads a PBM image from a file and returns a struct that represents the image.
 func ReadPBM(filename string) (*PBM, error) {
 	file, err := os.Open(filename)
@@ -14,6 +14,15 @@ type PGM struct{
     Max int
 }
 
+// newPGM returns an empty P2 image of the given size and max value.
+func newPGM(width, height, max int) *PGM{
+	data := make([][]uint8, height)
+	for i := range data {
+		data[i] = make([]uint8, width)
+	}
+	return &PGM{Data: data, Width: width, Height: height, MagicNumber: "P2", Max: max}
+}
+
 func ReadPGM(filename string) (*PGM, error){
 	file, err := os.Open(filename)
 	if err != nil{
@@ -209,24 +218,7 @@ func (pgm *PGM) Rotate90CW(){
 	pgm.Data = newData
 }
 
-// ToPBM converts the PGM image to PBM.
+// ToPBM converts the PGM image to PBM with a threshold of Max/2.
 func (pgm *PGM) ToPBM() *PBM{
-	var pbm PBM
-	pbm.MagicNumber = "P1"
-	pbm.Width = pgm.Width
-	pbm.Height = pgm.Height
-	pbm.Data = make([][]bool, pbm.Height)
-	for i := range pbm.Data {
-		pbm.Data[i] = make([]bool, pbm.Width)
-	}
-	for i := 0; i < pgm.Height; i++ {
-		for j := 0; j < pgm.Width; j++ {
-			if pgm.Data[i][j] > uint8(pgm.Max/2) {
-				pbm.Data[i][j] = true
-			} else {
-				pbm.Data[i][j] = false
-			}
-		}
-	}
-	return &pbm
+	return pgm.ToPBMWith(ThresholdOptions{Method: ThresholdFixed, Threshold: pgm.Max / 2})
 }
\ No newline at end of file
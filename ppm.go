@@ -22,6 +22,25 @@ type Point struct{
     X, Y int
 }
 
+// newPPM returns an empty P3 image of the given size and max value.
+func newPPM(width, height, max int) *PPM{
+	data := make([][]Pixel, height)
+	for i := range data {
+		data[i] = make([]Pixel, width)
+	}
+	return &PPM{Data: data, Width: width, Height: height, MagicNumber: "P3", Max: max}
+}
+
+// clone returns a deep copy of the PPM image.
+func (ppm *PPM) clone() *PPM{
+	c := *ppm
+	c.Data = make([][]Pixel, ppm.Height)
+	for i := range c.Data {
+		c.Data[i] = append([]Pixel(nil), ppm.Data[i]...)
+	}
+	return &c
+}
+
 // ReadPPM reads a PPM image from a file and returns a struct that represents the image.
 func ReadPPM(filename string) (*PPM, error){
 	file, err := os.Open(filename)
@@ -221,45 +240,14 @@ func (ppm *PPM) Rotate90CW(){
 	ppm.Data = newData
 }
 
-// ToPGM converts the PPM image to PGM.
+// ToPGM converts the PPM image to PGM using Rec. 601 luma.
 func (ppm *PPM) ToPGM() *PGM{
-	var pgm PGM
-	pgm.MagicNumber = "P2"
-	pgm.Width = ppm.Width
-	pgm.Height = ppm.Height
-	pgm.Max = ppm.Max
-	Data := make([][]uint8, pgm.Height)
-	for i := range Data{
-		Data[i] = make([]uint8, pgm.Width)
-	}
-	pgm.Data = Data
-	for i := 0; i < pgm.Height; i++{
-		for j := 0; j < pgm.Width; j++{
-			pgm.Data[i][j] = uint8((int(ppm.Data[i][j].R) + int(ppm.Data[i][j].G) + int(ppm.Data[i][j].B))/3)
-		}
-	}
-	return &pgm
+	return ppm.ToPGMWith(GrayscaleRec601)
 }
 
-// ToPBM converts the PPM image to PBM.
+// ToPBM converts the PPM image to PBM, setting the pixels whose Rec. 601 luma is above Max/2.
 func (ppm *PPM) ToPBM() *PBM{
-	var pbm PBM
-	pbm.MagicNumber = "P1"
-	pbm.Width = ppm.Width
-	pbm.Height = ppm.Height
-	Data := make([][]bool, pbm.Height)
-	for i := range Data{
-		Data[i] = make([]bool, pbm.Width)
-	}
-	pbm.Data = Data
-	for i := 0; i < pbm.Height; i++{
-		for j := 0; j < pbm.Width; j++{
-			if uint8((int(ppm.Data[i][j].R) + int(ppm.Data[i][j].G) + int(ppm.Data[i][j].B))/3) > uint8(ppm.Max/2){
-				pbm.Data[i][j] = true
-			}
-		}
-	}
-	return &pbm
+	return ppm.ToPGM().ToPBM()
 }
 
 // DrawLine draws a line between two points.
@@ -0,0 +1,290 @@
+package netpbm
+
+import (
+	"math"
+	"sort"
+)
+
+// QuantizeMethod selects the palette generation algorithm used by PPM.Quantize.
+type QuantizeMethod int
+
+const (
+	// MedianCut recursively splits the color box with the widest channel range at its median.
+	MedianCut QuantizeMethod = iota
+	// Octree merges the leaves of an 8 level color octree until n colors remain.
+	Octree
+	// KMeans refines a median cut palette with Lloyd iterations.
+	KMeans
+)
+
+// colorCount is a distinct color of an image and its number of pixels.
+type colorCount struct {
+	color Pixel
+	count int
+}
+
+// colorCounts returns the distinct colors of the PPM image.
+func (ppm *PPM) colorCounts() []colorCount{
+	counts := make(map[Pixel]int)
+	for i := 0; i < ppm.Height; i++ {
+		for j := 0; j < ppm.Width; j++ {
+			counts[ppm.Data[i][j]]++
+		}
+	}
+	colors := make([]colorCount, 0, len(counts))
+	for c, n := range counts {
+		colors = append(colors, colorCount{c, n})
+	}
+	// Map iteration order is random, sort to keep the results deterministic
+	sort.Slice(colors, func(i, j int) bool{
+		a, b := colors[i].color, colors[j].color
+		if a.R != b.R {
+			return a.R < b.R
+		}
+		if a.G != b.G {
+			return a.G < b.G
+		}
+		return a.B < b.B
+	})
+	return colors
+}
+
+// Palette returns a palette of at most n colors representing the PPM image.
+func (ppm *PPM) Palette(n int, method QuantizeMethod) []Pixel{
+	if n <= 0 {
+		return nil
+	}
+	colors := ppm.colorCounts()
+	if len(colors) <= n {
+		palette := make([]Pixel, len(colors))
+		for i, c := range colors {
+			palette[i] = c.color
+		}
+		return palette
+	}
+	switch method {
+	case Octree:
+		return octreePalette(colors, n)
+	case KMeans:
+		return kMeansPalette(colors, medianCutPalette(colors, n), 20)
+	default:
+		return medianCutPalette(colors, n)
+	}
+}
+
+// Quantize returns a copy of the PPM image reduced to at most n colors, and its palette.
+func (ppm *PPM) Quantize(n int, method QuantizeMethod, dither DitherOptions) (*PPM, []Pixel){
+	palette := ppm.Palette(n, method)
+	quantized := ppm.clone()
+	quantized.DitherToPalette(palette, dither)
+	return quantized, palette
+}
+
+// channelValue returns the channel c (0 = R, 1 = G, 2 = B) of the pixel.
+func channelValue(p Pixel, c int) uint8{
+	switch c {
+	case 0:
+		return p.R
+	case 1:
+		return p.G
+	default:
+		return p.B
+	}
+}
+
+// meanColor returns the mean of the colors weighted by their count.
+func meanColor(colors []colorCount) Pixel{
+	var r, g, b, total float64
+	for _, c := range colors {
+		n := float64(c.count)
+		r += float64(c.color.R) * n
+		g += float64(c.color.G) * n
+		b += float64(c.color.B) * n
+		total += n
+	}
+	return Pixel{uint8(math.Round(r / total)), uint8(math.Round(g / total)), uint8(math.Round(b / total))}
+}
+
+// medianCutPalette returns n colors computed with the median cut algorithm.
+func medianCutPalette(colors []colorCount, n int) []Pixel{
+	boxes := [][]colorCount{append([]colorCount(nil), colors...)}
+	for len(boxes) < n {
+		// Split the box with the widest channel range
+		best, bestChannel, bestRange := -1, 0, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			for c := 0; c < 3; c++ {
+				lo, hi := 255, 0
+				for _, cc := range box {
+					v := int(channelValue(cc.color, c))
+					lo, hi = min(lo, v), max(hi, v)
+				}
+				if hi-lo > bestRange {
+					best, bestChannel, bestRange = i, c, hi-lo
+				}
+			}
+		}
+		if best < 0 {
+			break
+		}
+		box := boxes[best]
+		sort.Slice(box, func(i, j int) bool{
+			return channelValue(box[i].color, bestChannel) < channelValue(box[j].color, bestChannel)
+		})
+		total := 0
+		for _, c := range box {
+			total += c.count
+		}
+		split, seen := 1, box[0].count
+		for split < len(box)-1 && seen+box[split].count <= total/2 {
+			seen += box[split].count
+			split++
+		}
+		boxes[best] = box[:split]
+		boxes = append(boxes, box[split:])
+	}
+	palette := make([]Pixel, len(boxes))
+	for i, box := range boxes {
+		palette[i] = meanColor(box)
+	}
+	return palette
+}
+
+// octreeNode is a node of the color octree used by octreePalette.
+type octreeNode struct {
+	children   [8]*octreeNode
+	r, g, b    float64
+	count      int
+	leaf       bool
+	level      int
+	childCount int
+}
+
+// octreePalette returns at most n colors computed with an octree color quantizer.
+func octreePalette(colors []colorCount, n int) []Pixel{
+	const depth = 8
+	root := &octreeNode{}
+	levels := make([][]*octreeNode, depth)
+	leaves := 0
+	for _, c := range colors {
+		node := root
+		for level := 0; level < depth; level++ {
+			shift := 7 - uint(level)
+			index := int((c.color.R>>shift)&1)<<2 | int((c.color.G>>shift)&1)<<1 | int((c.color.B>>shift)&1)
+			if node.children[index] == nil {
+				child := &octreeNode{level: level + 1}
+				node.children[index] = child
+				node.childCount++
+				if level+1 == depth {
+					child.leaf = true
+					leaves++
+				} else {
+					levels[level+1] = append(levels[level+1], child)
+				}
+			}
+			node = node.children[index]
+		}
+		node.r += float64(c.color.R) * float64(c.count)
+		node.g += float64(c.color.G) * float64(c.count)
+		node.b += float64(c.color.B) * float64(c.count)
+		node.count += c.count
+	}
+	levels[0] = []*octreeNode{root}
+
+	// Merge the children of the deepest nodes, least populated first
+	for level := depth - 1; level >= 0 && leaves > n; level-- {
+		nodes := levels[level]
+		for _, node := range nodes {
+			node.count = subtreeCount(node)
+		}
+		sort.SliceStable(nodes, func(i, j int) bool{
+			return nodes[i].count < nodes[j].count
+		})
+		for _, node := range nodes {
+			if leaves <= n {
+				break
+			}
+			if node.leaf {
+				continue
+			}
+			node.r, node.g, node.b, node.count = 0, 0, 0, 0
+			for i, child := range node.children {
+				if child == nil {
+					continue
+				}
+				node.r += child.r
+				node.g += child.g
+				node.b += child.b
+				node.count += child.count
+				node.children[i] = nil
+			}
+			leaves -= node.childCount - 1
+			node.childCount = 0
+			node.leaf = true
+		}
+	}
+
+	var palette []Pixel
+	var collect func(node *octreeNode)
+	collect = func(node *octreeNode){
+		if node.leaf {
+			if node.count > 0 {
+				total := float64(node.count)
+				palette = append(palette, Pixel{uint8(math.Round(node.r / total)), uint8(math.Round(node.g / total)), uint8(math.Round(node.b / total))})
+			}
+			return
+		}
+		for _, child := range node.children {
+			if child != nil {
+				collect(child)
+			}
+		}
+	}
+	collect(root)
+	return palette
+}
+
+// subtreeCount returns the number of pixels below an octree node.
+func subtreeCount(node *octreeNode) int{
+	if node.leaf {
+		return node.count
+	}
+	total := 0
+	for _, child := range node.children {
+		if child != nil {
+			total += subtreeCount(child)
+		}
+	}
+	return total
+}
+
+// kMeansPalette refines the palette with at most iterations rounds of k-means.
+func kMeansPalette(colors []colorCount, palette []Pixel, iterations int) []Pixel{
+	palette = append([]Pixel(nil), palette...)
+	assignment := make([]int, len(colors))
+	for it := 0; it < iterations; it++ {
+		changed := it == 0
+		for i, c := range colors {
+			k := nearestColor(palette, float64(c.color.R), float64(c.color.G), float64(c.color.B))
+			if k != assignment[i] {
+				assignment[i] = k
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+		clusters := make([][]colorCount, len(palette))
+		for i, c := range colors {
+			clusters[assignment[i]] = append(clusters[assignment[i]], c)
+		}
+		for k, cluster := range clusters {
+			if len(cluster) > 0 {
+				palette[k] = meanColor(cluster)
+			}
+		}
+	}
+	return palette
+}
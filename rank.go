@@ -0,0 +1,221 @@
+package netpbm
+
+import "math"
+
+// WindowShape selects the neighbourhood used by the rank filters.
+type WindowShape int
+
+const (
+	// WindowSquare uses the (2*radius+1)² square around each pixel.
+	WindowSquare WindowShape = iota
+	// WindowCircle uses the pixels within radius of each pixel.
+	WindowCircle
+)
+
+// windowSpans returns, for each row offset -radius..radius, the half width of the window.
+func windowSpans(radius int, shape WindowShape) []int{
+	if radius < 0 {
+		radius = 0
+	}
+	spans := make([]int, 2*radius+1)
+	for dy := -radius; dy <= radius; dy++ {
+		if shape == WindowCircle {
+			spans[dy+radius] = int(math.Sqrt(float64(radius*radius - dy*dy)))
+		} else {
+			spans[dy+radius] = radius
+		}
+	}
+	return spans
+}
+
+// rankHistogram is a 256 bin histogram with a coarse 16 bin index so that
+// looking up a rank costs at most 32 steps.
+type rankHistogram struct {
+	fine   [256]int
+	coarse [16]int
+	count  int
+}
+
+func (h *rankHistogram) add(v uint8){
+	h.fine[v]++
+	h.coarse[v>>4]++
+	h.count++
+}
+
+func (h *rankHistogram) remove(v uint8){
+	h.fine[v]--
+	h.coarse[v>>4]--
+	h.count--
+}
+
+// rank returns the value with the given 0-based rank.
+func (h *rankHistogram) rank(r int) uint8{
+	c := 0
+	for ; c < 15; c++ {
+		if r < h.coarse[c] {
+			break
+		}
+		r -= h.coarse[c]
+	}
+	v := c << 4
+	for ; v < c<<4+15; v++ {
+		if r < h.fine[v] {
+			break
+		}
+		r -= h.fine[v]
+	}
+	return uint8(v)
+}
+
+// rankFilter applies a percentile filter (0 = min, 50 = median, 100 = max) to a channel,
+// sliding a histogram along each row. Pixels outside the image repeat the border.
+func rankFilter(data [][]uint8, width, height, radius int, shape WindowShape, percentile float64) [][]uint8{
+	spans := windowSpans(radius, shape)
+	r := len(spans) / 2
+	at := func(x, y int) uint8{
+		x, _ = edgeIndex(x, width, EdgeClamp)
+		y, _ = edgeIndex(y, height, EdgeClamp)
+		return data[y][x]
+	}
+	if percentile < 0 {
+		percentile = 0
+	} else if percentile > 100 {
+		percentile = 100
+	}
+
+	out := make([][]uint8, height)
+	for y := 0; y < height; y++ {
+		out[y] = make([]uint8, width)
+		var h rankHistogram
+		for dy := -r; dy <= r; dy++ {
+			for dx := -spans[dy+r]; dx <= spans[dy+r]; dx++ {
+				h.add(at(dx, y+dy))
+			}
+		}
+		target := int(math.Round(percentile / 100 * float64(h.count-1)))
+		for x := 0; x < width; x++ {
+			if x > 0 {
+				for dy := -r; dy <= r; dy++ {
+					s := spans[dy+r]
+					h.remove(at(x-s-1, y+dy))
+					h.add(at(x+s, y+dy))
+				}
+			}
+			out[y][x] = h.rank(target)
+		}
+	}
+	return out
+}
+
+// PercentileFilter replaces each pixel with the given percentile (0 to 100) of its neighbourhood.
+func (pgm *PGM) PercentileFilter(radius int, shape WindowShape, percentile float64){
+	pgm.Data = rankFilter(pgm.Data, pgm.Width, pgm.Height, radius, shape, percentile)
+}
+
+// MedianFilter replaces each pixel with the median of its neighbourhood.
+func (pgm *PGM) MedianFilter(radius int, shape WindowShape){
+	pgm.PercentileFilter(radius, shape, 50)
+}
+
+// MinFilter replaces each pixel with the minimum of its neighbourhood.
+func (pgm *PGM) MinFilter(radius int, shape WindowShape){
+	pgm.PercentileFilter(radius, shape, 0)
+}
+
+// MaxFilter replaces each pixel with the maximum of its neighbourhood.
+func (pgm *PGM) MaxFilter(radius int, shape WindowShape){
+	pgm.PercentileFilter(radius, shape, 100)
+}
+
+// channels returns the R, G and B channels of the PPM data.
+func (ppm *PPM) channels() (r, g, b [][]uint8){
+	r = make([][]uint8, ppm.Height)
+	g = make([][]uint8, ppm.Height)
+	b = make([][]uint8, ppm.Height)
+	for i := 0; i < ppm.Height; i++ {
+		r[i] = make([]uint8, ppm.Width)
+		g[i] = make([]uint8, ppm.Width)
+		b[i] = make([]uint8, ppm.Width)
+		for j := 0; j < ppm.Width; j++ {
+			r[i][j], g[i][j], b[i][j] = ppm.Data[i][j].R, ppm.Data[i][j].G, ppm.Data[i][j].B
+		}
+	}
+	return r, g, b
+}
+
+// setChannels writes the R, G and B channels back to the PPM data.
+func (ppm *PPM) setChannels(r, g, b [][]uint8){
+	for i := 0; i < ppm.Height; i++ {
+		for j := 0; j < ppm.Width; j++ {
+			ppm.Data[i][j] = Pixel{r[i][j], g[i][j], b[i][j]}
+		}
+	}
+}
+
+// PercentileFilter replaces each channel of each pixel with the given percentile
+// (0 to 100) of its neighbourhood. Channels are filtered independently.
+func (ppm *PPM) PercentileFilter(radius int, shape WindowShape, percentile float64){
+	r, g, b := ppm.channels()
+	r = rankFilter(r, ppm.Width, ppm.Height, radius, shape, percentile)
+	g = rankFilter(g, ppm.Width, ppm.Height, radius, shape, percentile)
+	b = rankFilter(b, ppm.Width, ppm.Height, radius, shape, percentile)
+	ppm.setChannels(r, g, b)
+}
+
+// MedianFilter replaces each channel of each pixel with the median of its neighbourhood.
+func (ppm *PPM) MedianFilter(radius int, shape WindowShape){
+	ppm.PercentileFilter(radius, shape, 50)
+}
+
+// MinFilter replaces each channel of each pixel with the minimum of its neighbourhood.
+func (ppm *PPM) MinFilter(radius int, shape WindowShape){
+	ppm.PercentileFilter(radius, shape, 0)
+}
+
+// MaxFilter replaces each channel of each pixel with the maximum of its neighbourhood.
+func (ppm *PPM) MaxFilter(radius int, shape WindowShape){
+	ppm.PercentileFilter(radius, shape, 100)
+}
+
+// VectorMedianFilter replaces each pixel with the pixel of its neighbourhood that
+// minimizes the sum of L1 distances to all the others. Unlike MedianFilter it never
+// creates new colors, but its cost grows with the square of the window area.
+func (ppm *PPM) VectorMedianFilter(radius int, shape WindowShape){
+	spans := windowSpans(radius, shape)
+	r := len(spans) / 2
+	newData := make([][]Pixel, ppm.Height)
+	window := make([]Pixel, 0, len(spans)*len(spans))
+	for y := 0; y < ppm.Height; y++ {
+		newData[y] = make([]Pixel, ppm.Width)
+		for x := 0; x < ppm.Width; x++ {
+			window = window[:0]
+			for dy := -r; dy <= r; dy++ {
+				sy, _ := edgeIndex(y+dy, ppm.Height, EdgeClamp)
+				for dx := -spans[dy+r]; dx <= spans[dy+r]; dx++ {
+					sx, _ := edgeIndex(x+dx, ppm.Width, EdgeClamp)
+					window = append(window, ppm.Data[sy][sx])
+				}
+			}
+			best, bestSum := window[0], -1
+			for _, p := range window {
+				sum := 0
+				for _, q := range window {
+					sum += absInt(int(p.R)-int(q.R)) + absInt(int(p.G)-int(q.G)) + absInt(int(p.B)-int(q.B))
+				}
+				if bestSum < 0 || sum < bestSum {
+					best, bestSum = p, sum
+				}
+			}
+			newData[y][x] = best
+		}
+	}
+	ppm.Data = newData
+}
+
+// absInt returns the absolute value of x.
+func absInt(x int) int{
+	if x < 0 {
+		return -x
+	}
+	return x
+}
\ No newline at end of file
@@ -0,0 +1,205 @@
+package netpbm
+
+import (
+	"container/heap"
+	"math"
+)
+
+// floodItem is a pixel waiting in a floodQueue to receive a label.
+type floodItem struct {
+	p        Point
+	label    int
+	priority float64
+	order    int
+}
+
+// floodQueue is a priority queue of pixels, lowest priority first and first in first
+// out among equal priorities.
+type floodQueue struct {
+	items []floodItem
+	count int
+}
+
+func (q *floodQueue) Len() int{
+	return len(q.items)
+}
+
+func (q *floodQueue) Less(i, j int) bool{
+	if q.items[i].priority != q.items[j].priority {
+		return q.items[i].priority < q.items[j].priority
+	}
+	return q.items[i].order < q.items[j].order
+}
+
+func (q *floodQueue) Swap(i, j int){
+	q.items[i], q.items[j] = q.items[j], q.items[i]
+}
+
+func (q *floodQueue) Push(x any){
+	q.items = append(q.items, x.(floodItem))
+}
+
+func (q *floodQueue) Pop() any{
+	item := q.items[len(q.items)-1]
+	q.items = q.items[:len(q.items)-1]
+	return item
+}
+
+// push adds a pixel to the queue.
+func (q *floodQueue) push(p Point, label int, priority float64){
+	heap.Push(q, floodItem{p, label, priority, q.count})
+	q.count++
+}
+
+// pop removes the pixel with the lowest priority from the queue.
+func (q *floodQueue) pop() floodItem{
+	return heap.Pop(q).(floodItem)
+}
+
+// Watershed floods the PGM image, usually a gradient magnitude, from the markers: the
+// non-zero labels of markers, which must have the size of the image. Pixels are
+// labelled in increasing order of value with the label of the region that reaches
+// them first, so that regions meet along the ridges of the image. Every pixel
+// connected to a marker is labelled; there are no watershed lines.
+func (pgm *PGM) Watershed(markers *LabelImage, connectivity Connectivity) *LabelImage{
+	labels := newLabelImage(pgm.Width, pgm.Height)
+	neighbours := connectivity.neighbours()
+	queue := &floodQueue{}
+	for y := 0; y < pgm.Height; y++ {
+		for x := 0; x < pgm.Width; x++ {
+			if label := markers.Data[y][x]; label != 0 {
+				queue.push(Point{x, y}, label, float64(pgm.Data[y][x]))
+			}
+		}
+	}
+	for queue.Len() > 0 {
+		item := queue.pop()
+		p := item.p
+		if labels.Data[p.Y][p.X] != 0 {
+			continue
+		}
+		labels.Data[p.Y][p.X] = item.label
+		for _, d := range neighbours {
+			nx, ny := p.X+d.X, p.Y+d.Y
+			if nx >= 0 && ny >= 0 && nx < pgm.Width && ny < pgm.Height && labels.Data[ny][nx] == 0 {
+				queue.push(Point{nx, ny}, item.label, float64(pgm.Data[ny][nx]))
+			}
+		}
+	}
+	return labels
+}
+
+// RegionGrow segments the PGM image by seeded region growing: each seed starts a region
+// labelled with its index plus one, and the pixel closest in value to the mean of an
+// adjacent region is added to it first. Pixels further than tolerance from the mean of
+// every adjacent region are left at 0.
+func (pgm *PGM) RegionGrow(seeds []Point, tolerance int, connectivity Connectivity) *LabelImage{
+	labels := newLabelImage(pgm.Width, pgm.Height)
+	neighbours := connectivity.neighbours()
+	sums := make([]float64, len(seeds)+1)
+	counts := make([]int, len(seeds)+1)
+	mean := func(label int) float64{
+		return sums[label] / float64(counts[label])
+	}
+	queue := &floodQueue{}
+	for i, seed := range seeds {
+		if seed.X < 0 || seed.Y < 0 || seed.X >= pgm.Width || seed.Y >= pgm.Height || labels.Data[seed.Y][seed.X] != 0 {
+			continue
+		}
+		labels.Data[seed.Y][seed.X] = i + 1
+		sums[i+1], counts[i+1] = float64(pgm.Data[seed.Y][seed.X]), 1
+	}
+	pushNeighbours := func(p Point, label int){
+		for _, d := range neighbours {
+			nx, ny := p.X+d.X, p.Y+d.Y
+			if nx >= 0 && ny >= 0 && nx < pgm.Width && ny < pgm.Height && labels.Data[ny][nx] == 0 {
+				queue.push(Point{nx, ny}, label, math.Abs(float64(pgm.Data[ny][nx])-mean(label)))
+			}
+		}
+	}
+	for i, seed := range seeds {
+		if counts[i+1] > 0 {
+			pushNeighbours(seed, i+1)
+		}
+	}
+	for queue.Len() > 0 {
+		item := queue.pop()
+		p := item.p
+		if labels.Data[p.Y][p.X] != 0 {
+			continue
+		}
+		// The mean may have moved since the pixel was queued
+		v := float64(pgm.Data[p.Y][p.X])
+		if math.Abs(v-mean(item.label)) > float64(tolerance) {
+			continue
+		}
+		labels.Data[p.Y][p.X] = item.label
+		sums[item.label] += v
+		counts[item.label]++
+		pushNeighbours(p, item.label)
+	}
+	return labels
+}
+
+// FloodFill returns a PBM image where the pixels connected to seed whose value is
+// within tolerance of the seed value are set.
+func (pgm *PGM) FloodFill(seed Point, tolerance int, connectivity Connectivity) *PBM{
+	mask := newPBM(pgm.Width, pgm.Height)
+	if seed.X < 0 || seed.Y < 0 || seed.X >= pgm.Width || seed.Y >= pgm.Height {
+		return mask
+	}
+	pgm.floodFill(seed, tolerance, connectivity, func(x, y int) bool{
+		if mask.Data[y][x] {
+			return false
+		}
+		mask.Data[y][x] = true
+		return true
+	})
+	return mask
+}
+
+// FloodFillSegment divides the PGM image into regions by flood filling, in scan order,
+// from each pixel not yet in a region, with the given tolerance around its value. It
+// returns the label image and the number of regions.
+func (pgm *PGM) FloodFillSegment(tolerance int, connectivity Connectivity) (*LabelImage, int){
+	labels := newLabelImage(pgm.Width, pgm.Height)
+	count := 0
+	for y := 0; y < pgm.Height; y++ {
+		for x := 0; x < pgm.Width; x++ {
+			if labels.Data[y][x] != 0 {
+				continue
+			}
+			count++
+			pgm.floodFill(Point{x, y}, tolerance, connectivity, func(x, y int) bool{
+				if labels.Data[y][x] != 0 {
+					return false
+				}
+				labels.Data[y][x] = count
+				return true
+			})
+		}
+	}
+	return labels, count
+}
+
+// floodFill visits the pixels connected to seed whose value is within tolerance of the
+// seed value. visit marks a pixel and returns false if it was already marked.
+func (pgm *PGM) floodFill(seed Point, tolerance int, connectivity Connectivity, visit func(x, y int) bool){
+	reference := int(pgm.Data[seed.Y][seed.X])
+	neighbours := connectivity.neighbours()
+	visit(seed.X, seed.Y)
+	stack := []Point{seed}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, d := range neighbours {
+			nx, ny := p.X+d.X, p.Y+d.Y
+			if nx < 0 || ny < 0 || nx >= pgm.Width || ny >= pgm.Height || absInt(int(pgm.Data[ny][nx])-reference) > tolerance {
+				continue
+			}
+			if visit(nx, ny) {
+				stack = append(stack, Point{nx, ny})
+			}
+		}
+	}
+}
\ No newline at end of file
@@ -0,0 +1,194 @@
+package netpbm
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// MatchMethod selects the score computed by MatchTemplate.
+type MatchMethod int
+
+const (
+	// MatchSSD is the sum of squared differences; lower is better.
+	MatchSSD MatchMethod = iota
+	// MatchSAD is the sum of absolute differences; lower is better.
+	MatchSAD
+	// MatchNCC is the normalized cross-correlation, from 0 to 1; higher is better.
+	MatchNCC
+	// MatchZNCC is the zero-mean normalized cross-correlation, from -1 to 1; higher is
+	// better. It is insensitive to changes of brightness and contrast.
+	MatchZNCC
+)
+
+// TemplateMatch holds the score of a template at each position of an image, indexed
+// by the position of the top left corner of the template.
+type TemplateMatch struct {
+	Scores                        [][]float64
+	Width, Height                 int
+	TemplateWidth, TemplateHeight int
+	Method                        MatchMethod
+}
+
+// Match is a position of a template and its score.
+type Match struct {
+	Position Point
+	Score    float64
+}
+
+// MatchTemplate returns the score of the template at every position where it fits
+// entirely inside the PGM image.
+func MatchTemplate(image, template *PGM, method MatchMethod) (*TemplateMatch, error){
+	if template.Width > image.Width || template.Height > image.Height || template.Width == 0 || template.Height == 0 {
+		return nil, fmt.Errorf("template of %dx%d does not fit in image of %dx%d", template.Width, template.Height, image.Width, image.Height)
+	}
+	return matchTemplate([][]float64{image.plane()}, [][]float64{template.plane()}, image.Width, image.Height, template.Width, template.Height, method), nil
+}
+
+// MatchTemplatePPM returns the score of the template at every position where it fits
+// entirely inside the PPM image, comparing the three channels together.
+func MatchTemplatePPM(image, template *PPM, method MatchMethod) (*TemplateMatch, error){
+	if template.Width > image.Width || template.Height > image.Height || template.Width == 0 || template.Height == 0 {
+		return nil, fmt.Errorf("template of %dx%d does not fit in image of %dx%d", template.Width, template.Height, image.Width, image.Height)
+	}
+	ir, ig, ib := image.planes()
+	tr, tg, tb := template.planes()
+	return matchTemplate([][]float64{ir, ig, ib}, [][]float64{tr, tg, tb}, image.Width, image.Height, template.Width, template.Height, method), nil
+}
+
+// matchTemplate computes the scores of a template over an image, both given as lists
+// of channel planes.
+func matchTemplate(image, template [][]float64, iw, ih, tw, th int, method MatchMethod) *TemplateMatch{
+	m := &TemplateMatch{
+		Width:          iw - tw + 1,
+		Height:         ih - th + 1,
+		TemplateWidth:  tw,
+		TemplateHeight: th,
+		Method:         method,
+	}
+	n := float64(tw * th * len(template))
+	sumT, sumTT := 0.0, 0.0
+	for _, t := range template {
+		for _, v := range t {
+			sumT += v
+			sumTT += v * v
+		}
+	}
+	m.Scores = make([][]float64, m.Height)
+	for y := 0; y < m.Height; y++ {
+		m.Scores[y] = make([]float64, m.Width)
+		for x := 0; x < m.Width; x++ {
+			sumI, sumII, sumIT, sumAbs := 0.0, 0.0, 0.0, 0.0
+			for c, t := range template {
+				img := image[c]
+				for ty := 0; ty < th; ty++ {
+					row := img[(y+ty)*iw+x : (y+ty)*iw+x+tw]
+					for tx, vi := range row {
+						vt := t[ty*tw+tx]
+						sumI += vi
+						sumII += vi * vi
+						sumIT += vi * vt
+						if method == MatchSAD {
+							sumAbs += math.Abs(vi - vt)
+						}
+					}
+				}
+			}
+			var score float64
+			switch method {
+			case MatchSAD:
+				score = sumAbs
+			case MatchNCC:
+				if d := math.Sqrt(sumII * sumTT); d > 0 {
+					score = sumIT / d
+				}
+			case MatchZNCC:
+				varI, varT := sumII-sumI*sumI/n, sumTT-sumT*sumT/n
+				if d := math.Sqrt(varI * varT); d > 1e-9 {
+					score = (sumIT - sumI*sumT/n) / d
+				}
+			default:
+				score = math.Max(sumII-2*sumIT+sumTT, 0)
+			}
+			m.Scores[y][x] = score
+		}
+	}
+	return m
+}
+
+// better reports whether score a is a better match than score b.
+func (m *TemplateMatch) better(a, b float64) bool{
+	if m.Method == MatchSSD || m.Method == MatchSAD {
+		return a < b
+	}
+	return a > b
+}
+
+// Best returns the position of the best match and its score.
+func (m *TemplateMatch) Best() Match{
+	best := Match{Point{0, 0}, m.Scores[0][0]}
+	for y := 0; y < m.Height; y++ {
+		for x := 0; x < m.Width; x++ {
+			if m.better(m.Scores[y][x], best.Score) {
+				best = Match{Point{x, y}, m.Scores[y][x]}
+			}
+		}
+	}
+	return best
+}
+
+// BestMatches returns up to n matches, best first, whose template windows do not
+// overlap by more than half of the template size in each direction.
+func (m *TemplateMatch) BestMatches(n int) []Match{
+	var candidates []Match
+	for y := 0; y < m.Height; y++ {
+		for x := 0; x < m.Width; x++ {
+			candidates = append(candidates, Match{Point{x, y}, m.Scores[y][x]})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool{
+		return m.better(candidates[i].Score, candidates[j].Score)
+	})
+	var matches []Match
+	for _, c := range candidates {
+		if len(matches) == n {
+			break
+		}
+		keep := true
+		for _, kept := range matches {
+			if 2*absInt(c.Position.X-kept.Position.X) < m.TemplateWidth && 2*absInt(c.Position.Y-kept.Position.Y) < m.TemplateHeight {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			matches = append(matches, c)
+		}
+	}
+	return matches
+}
+
+// ToPGM returns a PGM image of the scores with a max value of 255, stretched so that
+// the best score is 255 and the worst 0.
+func (m *TemplateMatch) ToPGM() *PGM{
+	pgm := newPGM(m.Width, m.Height, 255)
+	lowest, highest := math.Inf(1), math.Inf(-1)
+	for _, row := range m.Scores {
+		for _, v := range row {
+			lowest, highest = math.Min(lowest, v), math.Max(highest, v)
+		}
+	}
+	if highest == lowest {
+		return pgm
+	}
+	for y, row := range m.Scores {
+		for x, v := range row {
+			f := (v - lowest) / (highest - lowest)
+			if m.Method == MatchSSD || m.Method == MatchSAD {
+				f = 1 - f
+			}
+			pgm.Data[y][x] = clampToMax(f*255, 255)
+		}
+	}
+	return pgm
+}
\ No newline at end of file
@@ -0,0 +1,274 @@
+package netpbm
+
+import "math"
+
+// ThresholdMethod selects how ToPBMWith decides whether a pixel is set.
+type ThresholdMethod int
+
+const (
+	// ThresholdFixed uses ThresholdOptions.Threshold for the whole image.
+	ThresholdFixed ThresholdMethod = iota
+	// ThresholdOtsu picks the global threshold maximizing the between-class variance.
+	ThresholdOtsu
+	// ThresholdTriangle picks the global threshold farthest from the line joining
+	// the histogram peak to its far end.
+	ThresholdTriangle
+	// ThresholdKapur picks the global threshold maximizing the sum of the class entropies.
+	ThresholdKapur
+	// ThresholdMean compares each pixel to the mean of its window minus Threshold.
+	ThresholdMean
+	// ThresholdGaussian compares each pixel to the Gaussian-weighted mean of its window minus Threshold.
+	ThresholdGaussian
+	// ThresholdNiblack compares each pixel to mean + K*stddev of its window.
+	ThresholdNiblack
+	// ThresholdSauvola compares each pixel to mean*(1 + K*(stddev/R - 1)) of its window.
+	ThresholdSauvola
+)
+
+// ThresholdOptions configures ToPBMWith.
+// Zero values of Radius, K and R select the usual defaults for the method.
+type ThresholdOptions struct {
+	Method ThresholdMethod
+	// Threshold is the cut for ThresholdFixed and the offset subtracted from
+	// the local mean for ThresholdMean and ThresholdGaussian.
+	Threshold int
+	// Radius is the half size of the window of the local methods (default 7).
+	Radius int
+	// K is the stddev weight of Niblack (default -0.2) and Sauvola (default 0.5).
+	K float64
+	// R is the dynamic range of the stddev for Sauvola (default (Max+1)/2).
+	R float64
+	// Dither, when its Method is not DitherNone, dithers the image between 0 and Max
+	// instead of thresholding it.
+	Dither DitherOptions
+}
+
+// ToPBMWith converts the PGM image to PBM, setting the pixels brighter than the
+// threshold computed by the given method.
+func (pgm *PGM) ToPBMWith(options ThresholdOptions) *PBM{
+	if options.Dither.Method != DitherNone {
+		return pgm.ditherToPBM(options.Dither)
+	}
+	pbm := newPBM(pgm.Width, pgm.Height)
+	switch options.Method {
+	case ThresholdMean, ThresholdGaussian, ThresholdNiblack, ThresholdSauvola:
+		thresholds := pgm.localThresholds(options)
+		for i := 0; i < pgm.Height; i++ {
+			for j := 0; j < pgm.Width; j++ {
+				pbm.Data[i][j] = float64(pgm.Data[i][j]) > thresholds[i*pgm.Width+j]
+			}
+		}
+	default:
+		threshold := pgm.globalThreshold(options)
+		for i := 0; i < pgm.Height; i++ {
+			for j := 0; j < pgm.Width; j++ {
+				pbm.Data[i][j] = int(pgm.Data[i][j]) > threshold
+			}
+		}
+	}
+	return pbm
+}
+
+// ToPBMWith converts the grayscale PPM image to PBM, see PGM.ToPBMWith.
+func (ppm *PPM) ToPBMWith(options ThresholdOptions) *PBM{
+	return ppm.ToPGM().ToPBMWith(options)
+}
+
+// globalThreshold returns the threshold of the global methods.
+func (pgm *PGM) globalThreshold(options ThresholdOptions) int{
+	switch options.Method {
+	case ThresholdOtsu:
+		return otsuThreshold(pgm.Histogram().Counts)
+	case ThresholdTriangle:
+		return triangleThreshold(pgm.Histogram().Counts)
+	case ThresholdKapur:
+		return kapurThreshold(pgm.Histogram().Counts)
+	default:
+		return options.Threshold
+	}
+}
+
+// otsuThreshold returns the value maximizing the between-class variance.
+func otsuThreshold(histogram []int) int{
+	total, sum := 0, 0.0
+	for v, n := range histogram {
+		total += n
+		sum += float64(v * n)
+	}
+	best, bestVariance := 0, -1.0
+	count0, sum0 := 0, 0.0
+	for t := range histogram {
+		count0 += histogram[t]
+		sum0 += float64(t * histogram[t])
+		count1 := total - count0
+		if count0 == 0 || count1 == 0 {
+			continue
+		}
+		mean0 := sum0 / float64(count0)
+		mean1 := (sum - sum0) / float64(count1)
+		variance := float64(count0) * float64(count1) * (mean0 - mean1) * (mean0 - mean1)
+		if variance > bestVariance {
+			best, bestVariance = t, variance
+		}
+	}
+	return best
+}
+
+// triangleThreshold returns the value farthest from the line joining the histogram
+// peak to the end of the histogram on its longest side.
+func triangleThreshold(histogram []int) int{
+	first, last, peak := -1, 0, 0
+	for v, n := range histogram {
+		if n > 0 {
+			if first < 0 {
+				first = v
+			}
+			last = v
+		}
+		if n > histogram[peak] {
+			peak = v
+		}
+	}
+	if first < 0 {
+		return 0
+	}
+	end := first
+	if last-peak > peak-first {
+		end = last
+	}
+	if end == peak {
+		return peak
+	}
+	// Distance to the line up to a constant factor
+	dx, dy := float64(end-peak), float64(histogram[end]-histogram[peak])
+	best, bestDistance := peak, -1.0
+	step := 1
+	if end < peak {
+		step = -1
+	}
+	for v := peak; v != end; v += step {
+		distance := math.Abs(dy*float64(v-peak) - dx*float64(histogram[v]-histogram[peak]))
+		if distance > bestDistance {
+			best, bestDistance = v, distance
+		}
+	}
+	return best
+}
+
+// kapurThreshold returns the value maximizing the sum of the entropies of both classes.
+func kapurThreshold(histogram []int) int{
+	total := 0
+	for _, n := range histogram {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+	p := make([]float64, len(histogram))
+	for v, n := range histogram {
+		p[v] = float64(n) / float64(total)
+	}
+	best, bestEntropy := 0, math.Inf(-1)
+	for t := range histogram {
+		w0 := 0.0
+		for v := 0; v <= t; v++ {
+			w0 += p[v]
+		}
+		w1 := 1 - w0
+		if w0 <= 0 || w1 <= 0 {
+			continue
+		}
+		entropy := 0.0
+		for v := range p {
+			if p[v] == 0 {
+				continue
+			}
+			if v <= t {
+				entropy -= p[v] / w0 * math.Log(p[v]/w0)
+			} else {
+				entropy -= p[v] / w1 * math.Log(p[v]/w1)
+			}
+		}
+		if entropy > bestEntropy {
+			best, bestEntropy = t, entropy
+		}
+	}
+	return best
+}
+
+// localThresholds returns the threshold of each pixel for the local methods.
+func (pgm *PGM) localThresholds(options ThresholdOptions) []float64{
+	radius := options.Radius
+	if radius <= 0 {
+		radius = 7
+	}
+	width, height := pgm.Width, pgm.Height
+	plane := pgm.plane()
+	if options.Method == ThresholdGaussian {
+		thresholds := convolvePlane(plane, width, height, GaussianKernel(float64(radius)/3), EdgeClamp)
+		for i := range thresholds {
+			thresholds[i] -= float64(options.Threshold)
+		}
+		return thresholds
+	}
+
+	mean, stddev := localMeanStddev(plane, width, height, radius)
+	thresholds := make([]float64, len(plane))
+	k := options.K
+	switch options.Method {
+	case ThresholdNiblack:
+		if k == 0 {
+			k = -0.2
+		}
+		for i := range thresholds {
+			thresholds[i] = mean[i] + k*stddev[i]
+		}
+	case ThresholdSauvola:
+		if k == 0 {
+			k = 0.5
+		}
+		r := options.R
+		if r == 0 {
+			r = float64(pgm.Max+1) / 2
+		}
+		for i := range thresholds {
+			thresholds[i] = mean[i] * (1 + k*(stddev[i]/r-1))
+		}
+	default:
+		for i := range thresholds {
+			thresholds[i] = mean[i] - float64(options.Threshold)
+		}
+	}
+	return thresholds
+}
+
+// localMeanStddev returns the mean and standard deviation of the (2*radius+1)² window
+// around each pixel, computed with integral images. Windows are cropped to the image.
+func localMeanStddev(plane []float64, width, height, radius int) (mean, stddev []float64){
+	stride := width + 1
+	sum := make([]float64, stride*(height+1))
+	sumSq := make([]float64, stride*(height+1))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := plane[y*width+x]
+			i := (y+1)*stride + x + 1
+			sum[i] = v + sum[i-1] + sum[i-stride] - sum[i-stride-1]
+			sumSq[i] = v*v + sumSq[i-1] + sumSq[i-stride] - sumSq[i-stride-1]
+		}
+	}
+	mean = make([]float64, len(plane))
+	stddev = make([]float64, len(plane))
+	for y := 0; y < height; y++ {
+		y0, y1 := max(y-radius, 0), min(y+radius+1, height)
+		for x := 0; x < width; x++ {
+			x0, x1 := max(x-radius, 0), min(x+radius+1, width)
+			n := float64((y1 - y0) * (x1 - x0))
+			s := sum[y1*stride+x1] - sum[y0*stride+x1] - sum[y1*stride+x0] + sum[y0*stride+x0]
+			sq := sumSq[y1*stride+x1] - sumSq[y0*stride+x1] - sumSq[y1*stride+x0] + sumSq[y0*stride+x0]
+			m := s / n
+			mean[y*width+x] = m
+			stddev[y*width+x] = math.Sqrt(math.Max(sq/n-m*m, 0))
+		}
+	}
+	return mean, stddev
+}
\ No newline at end of file
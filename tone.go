@@ -0,0 +1,262 @@
+package netpbm
+
+import (
+	"math"
+	"sort"
+)
+
+// toneLUT returns the lookup table of f for the values 0 to max.
+// f works on values normalized to [0, 1].
+func toneLUT(max int, f func(v float64) float64) []uint8{
+	if max <= 0 {
+		return nil
+	}
+	lut := make([]uint8, max+1)
+	for v := range lut {
+		lut[v] = clampToMax(f(float64(v)/float64(max))*float64(max), max)
+	}
+	return lut
+}
+
+// brightnessContrast returns the tone function adding brightness (a fraction of Max)
+// and scaling around the mid value by contrast.
+func brightnessContrast(brightness, contrast float64) func(float64) float64{
+	return func(v float64) float64{
+		return (v-0.5)*contrast + 0.5 + brightness
+	}
+}
+
+// gammaCurve returns the tone function v^(1/gamma); gamma above 1 brightens the image.
+func gammaCurve(gamma float64) func(float64) float64{
+	return func(v float64) float64{
+		if gamma <= 0 {
+			return v
+		}
+		return math.Pow(v, 1/gamma)
+	}
+}
+
+// levelsCurve returns the tone function mapping [black, white] to [0, 1] with a midtone gamma.
+func levelsCurve(black, white, gamma float64) func(float64) float64{
+	return func(v float64) float64{
+		if white <= black {
+			if v >= white {
+				return 1
+			}
+			return 0
+		}
+		v = math.Max(0, math.Min(1, (v-black)/(white-black)))
+		if gamma <= 0 {
+			return v
+		}
+		return math.Pow(v, 1/gamma)
+	}
+}
+
+// curveLUT returns the lookup table of the monotone cubic curve going through the
+// control points, whose X (input) and Y (output) go from 0 to max.
+func curveLUT(points []Point, max int) []uint8{
+	pts := append([]Point(nil), points...)
+	sort.Slice(pts, func(i, j int) bool{
+		return pts[i].X < pts[j].X
+	})
+	// Drop points with the same input
+	unique := pts[:0]
+	for _, p := range pts {
+		if len(unique) == 0 || unique[len(unique)-1].X != p.X {
+			unique = append(unique, p)
+		}
+	}
+	pts = unique
+
+	if max <= 0 {
+		return nil
+	}
+	lut := make([]uint8, max+1)
+	if len(pts) == 0 {
+		for v := range lut {
+			lut[v] = uint8(v)
+		}
+		return lut
+	}
+	if len(pts) == 1 {
+		for v := range lut {
+			lut[v] = clampToMax(float64(pts[0].Y), max)
+		}
+		return lut
+	}
+
+	// Fritsch-Carlson tangents keep the curve monotone between points
+	n := len(pts)
+	slopes := make([]float64, n-1)
+	for i := 0; i < n-1; i++ {
+		slopes[i] = float64(pts[i+1].Y-pts[i].Y) / float64(pts[i+1].X-pts[i].X)
+	}
+	tangents := make([]float64, n)
+	tangents[0], tangents[n-1] = slopes[0], slopes[n-2]
+	for i := 1; i < n-1; i++ {
+		if slopes[i-1]*slopes[i] <= 0 {
+			tangents[i] = 0
+		} else {
+			tangents[i] = (slopes[i-1] + slopes[i]) / 2
+		}
+	}
+	for i := 0; i < n-1; i++ {
+		if slopes[i] == 0 {
+			tangents[i], tangents[i+1] = 0, 0
+			continue
+		}
+		a, b := tangents[i]/slopes[i], tangents[i+1]/slopes[i]
+		if s := a*a + b*b; s > 9 {
+			t := 3 / math.Sqrt(s)
+			tangents[i] = t * a * slopes[i]
+			tangents[i+1] = t * b * slopes[i]
+		}
+	}
+
+	segment := 0
+	for v := range lut {
+		switch {
+		case v <= pts[0].X:
+			lut[v] = clampToMax(float64(pts[0].Y), max)
+		case v >= pts[n-1].X:
+			lut[v] = clampToMax(float64(pts[n-1].Y), max)
+		default:
+			for pts[segment+1].X < v {
+				segment++
+			}
+			p0, p1 := pts[segment], pts[segment+1]
+			h := float64(p1.X - p0.X)
+			t := float64(v-p0.X) / h
+			t2, t3 := t*t, t*t*t
+			y := (2*t3-3*t2+1)*float64(p0.Y) + (t3-2*t2+t)*h*tangents[segment] +
+				(-2*t3+3*t2)*float64(p1.Y) + (t3-t2)*h*tangents[segment+1]
+			lut[v] = clampToMax(y, max)
+		}
+	}
+	return lut
+}
+
+// ApplyLUT replaces each value v of the PGM image with lut[v].
+// Values with no entry in the table are left unchanged.
+func (pgm *PGM) ApplyLUT(lut []uint8){
+	for i := 0; i < pgm.Height; i++ {
+		for j := 0; j < pgm.Width; j++ {
+			if v := pgm.Data[i][j]; int(v) < len(lut) {
+				pgm.Data[i][j] = lut[v]
+			}
+		}
+	}
+}
+
+// BrightnessContrast adds brightness times Max to the PGM image and scales it
+// around Max/2 by contrast (1 leaves the image unchanged).
+func (pgm *PGM) BrightnessContrast(brightness, contrast float64){
+	pgm.ApplyLUT(toneLUT(pgm.Max, brightnessContrast(brightness, contrast)))
+}
+
+// Gamma applies the gamma correction Max*(v/Max)^(1/gamma) to the PGM image.
+func (pgm *PGM) Gamma(gamma float64){
+	pgm.ApplyLUT(toneLUT(pgm.Max, gammaCurve(gamma)))
+}
+
+// Levels maps the values from black to white of the PGM image to 0..Max,
+// applying gamma to the midtones.
+func (pgm *PGM) Levels(black, white int, gamma float64){
+	pgm.ApplyLUT(toneLUT(pgm.Max, levelsCurve(float64(black)/float64(pgm.Max), float64(white)/float64(pgm.Max), gamma)))
+}
+
+// Curves applies the smooth monotone curve going through the control points to the PGM image.
+// X is the input value and Y the output value, both from 0 to Max.
+func (pgm *PGM) Curves(points []Point){
+	pgm.ApplyLUT(curveLUT(points, pgm.Max))
+}
+
+// ApplyLUT replaces each channel value v of the PPM image with lut[v].
+// Values with no entry in the table are left unchanged.
+func (ppm *PPM) ApplyLUT(lut []uint8){
+	apply := func(v uint8) uint8{
+		if int(v) < len(lut) {
+			return lut[v]
+		}
+		return v
+	}
+	for i := 0; i < ppm.Height; i++ {
+		for j := 0; j < ppm.Width; j++ {
+			p := &ppm.Data[i][j]
+			p.R, p.G, p.B = apply(p.R), apply(p.G), apply(p.B)
+		}
+	}
+}
+
+// BrightnessContrast adds brightness times Max to each channel of the PPM image and
+// scales it around Max/2 by contrast (1 leaves the image unchanged).
+func (ppm *PPM) BrightnessContrast(brightness, contrast float64){
+	ppm.ApplyLUT(toneLUT(ppm.Max, brightnessContrast(brightness, contrast)))
+}
+
+// Gamma applies the gamma correction Max*(v/Max)^(1/gamma) to each channel of the PPM image.
+func (ppm *PPM) Gamma(gamma float64){
+	ppm.ApplyLUT(toneLUT(ppm.Max, gammaCurve(gamma)))
+}
+
+// Levels maps the values from black to white of each channel of the PPM image to 0..Max,
+// applying gamma to the midtones.
+func (ppm *PPM) Levels(black, white int, gamma float64){
+	ppm.ApplyLUT(toneLUT(ppm.Max, levelsCurve(float64(black)/float64(ppm.Max), float64(white)/float64(ppm.Max), gamma)))
+}
+
+// Curves applies the smooth monotone curve going through the control points to each
+// channel of the PPM image. X is the input value and Y the output value, both from 0 to Max.
+func (ppm *PPM) Curves(points []Point){
+	ppm.ApplyLUT(curveLUT(points, ppm.Max))
+}
+
+// HueShift rotates the hue of each pixel of the PPM image by the given number of degrees.
+func (ppm *PPM) HueShift(degrees float64){
+	for i := 0; i < ppm.Height; i++ {
+		for j := 0; j < ppm.Width; j++ {
+			hsv := ppm.Data[i][j].ToHSV(ppm.Max)
+			hsv.H += degrees
+			ppm.Data[i][j] = hsv.ToPixel(ppm.Max)
+		}
+	}
+}
+
+// Saturation scales the distance of each pixel of the PPM image to its Rec. 601 luma
+// by factor: 0 gives a gray image and 1 leaves the image unchanged.
+func (ppm *PPM) Saturation(factor float64){
+	ppm.saturate(func(float64) float64{
+		return factor
+	})
+}
+
+// Vibrance increases the saturation of the PPM image by amount, weighted towards the
+// least saturated pixels so that already vivid colors are barely changed.
+func (ppm *PPM) Vibrance(amount float64){
+	ppm.saturate(func(saturation float64) float64{
+		return 1 + amount*(1-saturation)
+	})
+}
+
+// saturate scales the chroma of each pixel by the factor computed from its HSV saturation.
+func (ppm *PPM) saturate(factor func(saturation float64) float64){
+	for i := 0; i < ppm.Height; i++ {
+		for j := 0; j < ppm.Width; j++ {
+			p := ppm.Data[i][j]
+			r, g, b := float64(p.R), float64(p.G), float64(p.B)
+			hi := math.Max(r, math.Max(g, b))
+			saturation := 0.0
+			if hi > 0 {
+				saturation = (hi - math.Min(r, math.Min(g, b))) / hi
+			}
+			f := factor(saturation)
+			y := 0.299*r + 0.587*g + 0.114*b
+			ppm.Data[i][j] = Pixel{
+				clampToMax(y+f*(r-y), ppm.Max),
+				clampToMax(y+f*(g-y), ppm.Max),
+				clampToMax(y+f*(b-y), ppm.Max),
+			}
+		}
+	}
+}
\ No newline at end of file